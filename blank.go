@@ -0,0 +1,48 @@
+package uses
+
+import (
+	"sort"
+	"strconv"
+)
+
+// BlankImports maps each searched package that is blank-imported by
+// other searched packages to those importers. Only packages that were
+// type-checked from source are considered as importers.
+func (s *Snapshot) BlankImports() map[string][]string {
+	searched := make(map[string]bool)
+	for _, path := range s.Loaded {
+		searched[path] = true
+	}
+	importers := make(map[string][]string)
+	for importer, src := range s.sources {
+		for _, file := range src.files {
+			for _, spec := range file.Imports {
+				if spec.Name == nil || spec.Name.Name != "_" {
+					continue
+				}
+				path, err := strconv.Unquote(spec.Path.Value)
+				if err != nil || !searched[path] {
+					continue
+				}
+				importers[path] = append(importers[path], importer)
+			}
+		}
+	}
+
+	for path, list := range importers {
+		sort.Strings(list)
+		importers[path] = dedupStrings(list)
+	}
+	return importers
+}
+
+// dedupStrings removes adjacent duplicates from a sorted slice.
+func dedupStrings(list []string) []string {
+	var out []string
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package uses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlankImports(t *testing.T) {
+	ctx := newTestContext(t)
+	s := Load(ctx, []string{"blank/app", "blank/driver", "blank/util", "blank/other"})
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	// fmt isn't searched, and blank/util is imported by name.
+	want := map[string][]string{"blank/driver": {"blank/app", "blank/other"}}
+	if got := s.BlankImports(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBlankImportsSnapshot(t *testing.T) {
+	ctx := newTestContext(t)
+	s := Load(ctx, []string{"blank/app", "blank/other"})
+	if got := s.BlankImports(); len(got) != 0 {
+		t.Fatalf("got %v, want none: blank/driver isn't searched", got)
+	}
+	// blank/driver being searched by a later load doesn't make it
+	// searched in the first snapshot.
+	later := Load(ctx, []string{"blank/driver", "blank/app"})
+	if got := s.BlankImports(); len(got) != 0 {
+		t.Errorf("got %v after another load, want none", got)
+	}
+	want := map[string][]string{"blank/driver": {"blank/app"}}
+	if got := later.BlankImports(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v for the later load, want %v", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/importer"
+	"golang.org/x/tools/go/types"
+
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheVersion is part of every cache key. Bump it when the format of
+// cached data changes.
+const cacheVersion = "2"
+
+// DefaultCacheDir returns the directory of the package cache: $USES_CACHE
+// if set, or a directory in the user's cache directory.
+func DefaultCacheDir() string {
+	if dir := os.Getenv("USES_CACHE"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "uses")
+}
+
+// cacheKey identifies the state of a package's source: the build
+// configuration, its directory, the names, sizes and modification
+// times of its files, and the keys of the packages it imports, since their types
+// are part of the package's type information. It is empty if a file
+// can't be stat'ed or an import can't be found.
+func (ctx *Context) cacheKey(path, dir string, files, imports []string) string {
+	return ctx.sourceKey(path, dir, files, imports, make(map[string]bool))
+}
+
+// sourceKey implements cacheKey. visiting holds the imports whose
+// keys are being computed, to detect import cycles.
+func (ctx *Context) sourceKey(path, dir string, files, imports []string, visiting map[string]bool) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", cacheVersion, path, dir, ctx.buildConfig())
+	if !ctx.hashFiles(h, dir, files) {
+		return ""
+	}
+	for _, imp := range imports {
+		key := ctx.depKey(imp, visiting)
+		if key == "" {
+			return ""
+		}
+		fmt.Fprintf(h, "%s %s\n", imp, key)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// hashFiles writes the names, sizes and modification times of files
+// to w. It reports whether all of them could be stat'ed.
+func (ctx *Context) hashFiles(w io.Writer, dir string, files []string) bool {
+	for _, file := range files {
+		fi, err := os.Stat(filepath.Join(dir, file))
+		if err != nil {
+			return false
+		}
+		fmt.Fprintf(w, "%s %d %d\n", file, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return true
+}
+
+// depKey identifies the state of the imported package path, like
+// cacheKey. Keys are computed once per load.
+func (ctx *Context) depKey(path string, visiting map[string]bool) string {
+	if path == "unsafe" || path == "C" {
+		return path
+	}
+	if visiting[path] {
+		// An import cycle, which won't type-check anyway.
+		return ""
+	}
+	ctx.depKeysMu.Lock()
+	key, ok := ctx.depKeys[path]
+	ctx.depKeysMu.Unlock()
+	if ok {
+		return key
+	}
+	if buildPkg, err := ctx.findPackage(path); err == nil {
+		files := append(append([]string(nil), buildPkg.GoFiles...), buildPkg.CgoFiles...)
+		visiting[path] = true
+		key = ctx.sourceKey(path, buildPkg.Dir, files, buildPkg.Imports, visiting)
+		delete(visiting, path)
+	}
+	ctx.depKeysMu.Lock()
+	ctx.depKeys[path] = key
+	ctx.depKeysMu.Unlock()
+	return key
+}
+
+// buildConfig describes the settings of ctx.Build that select a
+// package's files.
+func (ctx *Context) buildConfig() string {
+	return fmt.Sprintf("%s/%s\n%s", ctx.Build.GOOS, ctx.Build.GOARCH, strings.Join(ctx.Build.BuildTags, ","))
+}
+
+// cacheFile returns the file caching the package path in dir. Each
+// directory and build configuration has its own file, so that
+// alternating between GOPATHs or platforms doesn't evict packages.
+func (ctx *Context) cacheFile(path, dir string) string {
+	name := fmt.Sprintf("%s\n%s\n%s", path, dir, ctx.buildConfig())
+	return filepath.Join(ctx.CacheDir, fmt.Sprintf("%x", sha256.Sum256([]byte(name))))
+}
+
+// checkCached is like checkFiles, but uses the package cache if
+// ctx.CacheDir is set. Packages read from the cache carry type
+// information only, like packages imported from gc export data.
+func (ctx *Context) checkCached(path, dir string, files, imports []string) loadResult {
+	if ctx.CacheDir == "" {
+		return ctx.checkFiles(path, dir, files)
+	}
+	key := ctx.cacheKey(path, dir, files, imports)
+	if key == "" {
+		return ctx.checkFiles(path, dir, files)
+	}
+
+	if data, err := ioutil.ReadFile(ctx.cacheFile(path, dir)); err == nil && bytes.HasPrefix(data, []byte(key+"\n")) {
+		ctx.importMu.Lock()
+		_, pkg, err := importer.ImportData(ctx.allImports, data[len(key)+1:])
+		ctx.importMu.Unlock()
+		if err == nil {
+			ctx.Log.Debugf("Loaded %s from the cache", path)
+			return loadResult{path: path, pkg: pkg}
+		}
+		ctx.Log.Debugf("Ignoring the cache of %s: %s", path, err)
+	}
+
+	res := ctx.checkFiles(path, dir, files)
+	if res.pkg != nil {
+		ctx.writeCache(path, dir, key, res.pkg)
+	}
+	return res
+}
+
+// writeCache stores pkg in the cache. Failing to do so only costs
+// performance, so errors are merely logged.
+func (ctx *Context) writeCache(path, dir, key string, pkg *types.Package) {
+	if err := os.MkdirAll(ctx.CacheDir, 0755); err != nil {
+		ctx.Log.Debugf("Couldn't create the cache: %s", err)
+		return
+	}
+	data := append([]byte(key+"\n"), importer.ExportData(pkg)...)
+
+	// Write to a temporary file first, so that concurrent runs
+	// never read partial data.
+	f, err := ioutil.TempFile(ctx.CacheDir, "tmp")
+	if err != nil {
+		ctx.Log.Debugf("Couldn't cache %s: %s", path, err)
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), ctx.cacheFile(path, dir))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		ctx.Log.Debugf("Couldn't cache %s: %s", path, err)
+	}
+}
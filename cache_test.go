@@ -0,0 +1,141 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uses-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q := Query{Rets: []string{"platform/b.T"}}
+	var want []string
+	for i, hit := range []bool{false, true} {
+		ctx := newTestContext(t)
+		ctx.Build.GOOS = "linux"
+		ctx.CacheDir = dir
+		s := Load(ctx, []string{"platform/a"})
+		if len(s.Errors) > 0 {
+			t.Fatal(s.Errors)
+		}
+		// Packages read from the cache carry no source.
+		if _, ok := s.sources["platform/a"]; ok == hit {
+			t.Errorf("load %d: got source %t, want %t", i, ok, !hit)
+		}
+		matches, err := s.Match(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := matchNames(matches)
+		if i == 0 {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("cached load: got %v, want %v", got, want)
+		}
+	}
+	if len(want) != 1 {
+		t.Errorf("got %v, want one match", want)
+	}
+}
+
+func TestCacheKeyDependencies(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "uses-gopath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	write := func(file, src string) {
+		file = filepath.Join(gopath, "src", file)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("x/x.go", "package x\n\nimport \"y\"\n\nfunc F() y.T { return 0 }\n")
+	write("y/y.go", "package y\n\ntype T int\n")
+
+	key := func() string {
+		ctx := NewContext()
+		ctx.Build.GOPATH = gopath
+		buildPkg, err := ctx.Build.Import("x", ".", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ctx.cacheKey("x", buildPkg.Dir, buildPkg.GoFiles, buildPkg.Imports)
+	}
+	before := key()
+	if before == "" || key() != before {
+		t.Fatalf("got unstable key %q", before)
+	}
+
+	write("y/y.go", "package y\n\ntype T string\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(gopath, "src", "y", "y.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if key() == before {
+		t.Error("changing a dependency didn't change the key")
+	}
+}
+
+func TestCacheDirs(t *testing.T) {
+	cache, err := ioutil.TempDir("", "uses-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+	// Two GOPATHs with the same package, whose files only differ in
+	// content.
+	var gopaths []string
+	mtime := time.Now().Add(-time.Hour)
+	for _, typ := range []string{"int64", "uint8"} {
+		gopath, err := ioutil.TempDir("", "uses-gopath")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(gopath)
+		file := filepath.Join(gopath, "src", "x", "x.go")
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte("package x\n\nfunc F() "+typ+" { return 0 }\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		gopaths = append(gopaths, gopath)
+	}
+
+	for i, tt := range []struct {
+		gopath int
+		hit    bool
+	}{{0, false}, {1, false}, {0, true}, {1, true}} {
+		ctx := NewContext()
+		ctx.Build.GOPATH = gopaths[tt.gopath]
+		ctx.CacheDir = cache
+		s := Load(ctx, []string{"x"})
+		if len(s.Errors) > 0 {
+			t.Fatal(s.Errors)
+		}
+		if _, ok := s.sources["x"]; ok == tt.hit {
+			t.Errorf("load %d: got source %t, want %t", i+1, ok, !tt.hit)
+		}
+		want := []string{"int64", "uint8"}[tt.gopath]
+		if got := TypeString(s.funcs[0].Type().(*types.Signature).Results().At(0).Type()); got != want {
+			t.Errorf("load %d: got %s, want %s", i+1, got, want)
+		}
+	}
+}
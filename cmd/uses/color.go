@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+)
+
+// useColor decides whether to color output, given the value of
+// -color. In auto mode, setting the NO_COLOR environment variable
+// disables color, as suggested by https://no-color.org.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
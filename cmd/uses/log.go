@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type logLevel int
+
+const (
+	levelError logLevel = iota
+	levelWarn
+	levelInfo
+	levelDebug
+)
+
+// logger writes diagnostics to stderr. Stdout is reserved for
+// results.
+type logger struct {
+	w     io.Writer
+	level logLevel
+}
+
+var log = &logger{w: os.Stderr, level: levelWarn}
+
+func (l *logger) logf(level logLevel, format string, args ...interface{}) {
+	if level > l.level {
+		return
+	}
+	fmt.Fprintf(l.w, format+"\n", args...)
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) { l.logf(levelError, format, args...) }
+func (l *logger) Warnf(format string, args ...interface{})  { l.logf(levelWarn, format, args...) }
+func (l *logger) Infof(format string, args ...interface{})  { l.logf(levelInfo, format, args...) }
+func (l *logger) Debugf(format string, args ...interface{}) { l.logf(levelDebug, format, args...) }
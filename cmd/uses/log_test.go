@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		level logLevel
+		want  []string
+	}{
+		{levelError, []string{"error"}},
+		{levelWarn, []string{"error", "warn"}},
+		{levelInfo, []string{"error", "warn", "info"}},
+		{levelDebug, []string{"error", "warn", "info", "debug"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		l := &logger{w: &buf, level: tt.level}
+		l.Errorf("error")
+		l.Warnf("warn")
+		l.Infof("info")
+		l.Debugf("debug")
+		if got := strings.Fields(buf.String()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("level %d: got %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestLogFlags(t *testing.T) {
+	const (
+		errorMsg = "Couldn't import missing"
+		infoMsg  = "Only showing 1 of"
+	)
+	tests := []struct {
+		flag        string
+		error, info bool
+	}{
+		{"-quiet", true, false},
+		{"-v", true, true},
+		{"", true, false},
+	}
+	w := log.w
+	defer func() { log.w = w }()
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		log.w = &buf
+		args := []string{"-pkgs", "resolve,missing", "-rets", "_", "-limit", "1"}
+		if tt.flag != "" {
+			args = append(args, tt.flag)
+		}
+		_, code := runArgs(t, args...)
+		if code != exitError {
+			t.Errorf("%q: got exit status %d, want %d", tt.flag, code, exitError)
+		}
+		stderr := buf.String()
+		if strings.Contains(stderr, errorMsg) != tt.error {
+			t.Errorf("%q: error logged = %t, want %t:\n%s", tt.flag, !tt.error, tt.error, stderr)
+		}
+		if strings.Contains(stderr, infoMsg) != tt.info {
+			t.Errorf("%q: info logged = %t, want %t:\n%s", tt.flag, !tt.info, tt.info, stderr)
+		}
+	}
+}
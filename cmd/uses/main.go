@@ -0,0 +1,862 @@
+package main
+
+import (
+	"honnef.co/go/uses"
+
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSlice) Set(val string) error {
+	*s = strings.Split(val, ",")
+	return nil
+}
+
+// repeatedString is a flag that may be specified multiple times, each
+// occurrence being a single value. Unlike stringSlice, commas aren't
+// special, which allows specifying types like func(int, string).
+type repeatedString []string
+
+func (s *repeatedString) String() string {
+	return strings.Join(*s, " ")
+}
+
+func (s *repeatedString) Set(val string) error {
+	*s = append(*s, val)
+	return nil
+}
+
+var (
+	packages  stringSlice
+	arguments stringSlice
+	returns   stringSlice
+	argList   repeatedString
+	retList   repeatedString
+	and       bool
+
+	returnsErrorType    string
+	constructors        bool
+	zeroArgConstructors bool
+	suggestInterfaces   bool
+	minDeps             int
+	usesPkg             string
+	verbose             bool
+	quiet               bool
+
+	returnsPtrImplementing string
+	catchAll               bool
+	listMatchingPackages   bool
+	returnsCleanup         bool
+	directive              string
+	minMethods             int
+	assignableTo           stringSlice
+	assignableToMode       string
+	selfConsistentReturns  bool
+	gopath                 string
+	variadicOf             string
+	matchBlankImport       bool
+	fields                 bool
+	tag                    string
+	methodCoverage         string
+	shape                  bool
+	snippet                bool
+	concreteArgs           int
+	interfaceArgs          int
+	describeOpts           bool
+	producers              bool
+	retElem                string
+	deadline               time.Duration
+	cleanVendor            bool
+	timeout                time.Duration
+	partial                bool
+	maxPackages            int
+	jobs                   int
+	pos                    bool
+	doc                    bool
+	kind                   string
+	positional             bool
+	nargs                  string
+	nrets                  string
+	buildTags              stringSlice
+	goos                   string
+	goarch                 string
+	count                  bool
+	deref                  bool
+	tmpl                   string
+	returnsError           string
+	variadic               bool
+	ignoreCase             bool
+	excludePkgs            stringSlice
+	excludeTypes           stringSlice
+	queryExpr              string
+	quietOutput            bool
+	underlying             bool
+	anyChanDir             bool
+	unqualified            bool
+	tests                  bool
+	noCache                bool
+	limit                  int
+	minMatches             int
+	sameArg                bool
+	strictImport           bool
+	jsonErrors             bool
+	overrides              bool
+	structByType           bool
+	contextNotFirst        bool
+	groupBy                string
+	sortBy                 string
+	stable                 bool
+	watch                  bool
+	resultErrorPairing     bool
+	forwardsResults        bool
+	color                  string
+	assignableSig          string
+	outParams              bool
+	hasBody                bool
+	externalOnly           bool
+	reachableFromExported  bool
+	regex                  bool
+	name                   string
+	argName                string
+	retName                string
+	searchTypes            bool
+	implements             stringSlice
+	hasField               stringSlice
+	duplicateArgs          bool
+	format                 string
+	returnsPointer         bool
+	returnsErrorOnly       bool
+	returnsBool            bool
+	returnsString          bool
+	noPromotedStdlib       bool
+	promoted               bool
+	assignable             bool
+	byConfidence           bool
+)
+
+func init() {
+	flag.Var(&packages, withTokens("pkgs", "-", "@file"), "Comma-separated list of packages to search for functions. Entries prefixed with ! exclude packages selected by earlier entries. - and @file read packages from stdin or file, one per line.")
+	flag.Var(&excludePkgs, "exclude-pkgs", "Comma-separated list of package patterns, such as .../internal/..., not to load.")
+	flag.Var(&arguments, withTokens("args", uses.AnyType), "Comma-separated list of argument types to match.")
+	flag.Var(&returns, withTokens("rets", uses.AnyType), "Comma-separated list of return types to match.")
+	flag.Var(&argList, withTokens("arg", uses.AnyType), "Argument type to match. May be repeated; commas are part of the type.")
+	flag.Var(&retList, withTokens("ret", uses.AnyType), "Return type to match. May be repeated; commas are part of the type.")
+	flag.Var(&excludeTypes, "exclude-types", "Comma-separated list of types that never match -args and -rets, such as interface{}.")
+	flag.StringVar(&queryExpr, withTokens("query", uses.AnyType), "", "Boolean expression over parameter and result types, such as '(arg:io.Reader | arg:io.Writer) & ret:error'. Replaces -args, -rets and -and.")
+	flag.BoolVar(&and, "and", false, "Use AND instead of OR for matching functions.")
+	flag.BoolVar(&sameArg, "same-arg", false, "Require a single parameter to be assignable to all of -args, such as an io.ReadCloser for io.Reader,io.Closer. Implies -assignable.")
+	flag.IntVar(&minMatches, "min-matches", 0, "Only match functions that use at least this many of the types in -args and -rets, instead of any or, with -and, all of them.")
+	flag.BoolVar(&assignable, "assignable", false, "Match argument and return types by assignability instead of exact equality.")
+	flag.Var(newEnum(&returnsError, "", "last", "any", "none"), "returns-error", "Only match functions returning an error as their last result (last), as any result (any), or not at all (none).")
+	flag.BoolVar(&underlying, "underlying", false, "Match argument and return types by their underlying types, so that int matches time.Duration.")
+	flag.StringVar(&returnsErrorType, "returns-error-type", "", "Only match functions whose last return value implements this interface.")
+
+	flag.BoolVar(&constructors, "constructors", false, "Only match constructors, grouped by the type they construct.")
+	flag.BoolVar(&zeroArgConstructors, "zero-arg-constructors", false, "Only match constructors that take no arguments.")
+	flag.BoolVar(&suggestInterfaces, "suggest-interfaces", false, "Suggest interfaces for concrete parameters that are only used for their methods. Expensive.")
+	flag.IntVar(&minDeps, "min-deps", 0, "Only match functions whose signature references types from at least this many packages.")
+	flag.StringVar(&usesPkg, "uses-pkg", "", "Only match functions whose signature references types from this package.")
+	flag.StringVar(&returnsPtrImplementing, "returns-ptr-implementing", "", "Only match functions returning a pointer that implements this interface.")
+	flag.BoolVar(&catchAll, "catch-all", false, "Only match functions whose final parameter is ...interface{}.")
+	flag.BoolVar(&returnsCleanup, "returns-cleanup", false, "Only match functions returning a value and a func() or func() error cleanup function.")
+	flag.StringVar(&directive, "directive", "", "Only match functions annotated with a directive comment with this prefix, such as go:noinline. Requires source.")
+	flag.IntVar(&minMethods, "min-methods", 0, "Only match methods whose receiver type has at least this many methods.")
+	flag.Var(&assignableTo, "assignable-to", "Comma-separated list of types that a return value has to be assignable to.")
+	flag.Var(newEnum(&assignableToMode, "any", "any", "all"), "assignable-to-mode", "Whether a return value has to be assignable to any or all of the -assignable-to types.")
+	flag.BoolVar(&selfConsistentReturns, "self-consistent-returns", false, "Only match functions returning both a concrete type and an interface that it implements.")
+	flag.BoolVar(&variadic, "variadic", false, "Only match variadic functions.")
+	flag.StringVar(&variadicOf, "variadic-of", "", "Only match variadic functions whose variadic parameter has this element type.")
+	flag.BoolVar(&matchBlankImport, "match-blank-import", false, "Report searched packages that other searched packages import only for their side effects.")
+	flag.BoolVar(&fields, "fields", false, "Search struct fields instead of functions. -args filters the field types.")
+	flag.BoolVar(&fields, "struct-fields", false, "Alias for -fields.")
+	flag.StringVar(&tag, "tag", "", "In -fields mode, only match fields with this struct tag key, or key:value.")
+	flag.StringVar(&methodCoverage, "method-coverage", "", "Report which searched types implement which methods of this interface.")
+	flag.BoolVar(&snippet, "snippet", false, "Print an import and an example call with placeholder arguments for each match.")
+	flag.BoolVar(&shape, "shape", false, "Group matches by their signature's types, ignoring names and receivers.")
+	flag.IntVar(&concreteArgs, "concrete-args", -1, "Only match functions with exactly this many parameters of concrete types.")
+	flag.IntVar(&interfaceArgs, "interface-args", -1, "Only match functions with exactly this many parameters of interface types.")
+	flag.BoolVar(&describeOpts, "describe-options", false, "Print a JSON description of all options and exit.")
+	flag.BoolVar(&producers, "producers", false, "Only match functions returning a channel that can be received from.")
+	flag.StringVar(&retElem, "ret-elem", "", "With -producers, only match channels of this element type.")
+	flag.BoolVar(&overrides, "overrides", false, "Report methods that shadow methods promoted from embedded fields.")
+	flag.BoolVar(&structByType, "struct-by-type", false, "Compare struct types by their field types only, ignoring field names and tags.")
+	flag.BoolVar(&contextNotFirst, "context-not-first", false, "Only match functions that take a context.Context, but not as their first parameter.")
+	flag.Var(newEnum(&sortBy, "package", "package", "name", "none"), "sort", "Print matches grouped by package, as a flat list sorted by name, or as a flat list in the order they were found (none).")
+	flag.BoolVar(&watch, "watch", false, "Keep running, and run the query again whenever a Go file of the searched packages changes.")
+	flag.BoolVar(&stable, "stable", false, "Print deterministic output, suitable for golden files: sorted, without colors, positions, docs or parameter names.")
+	flag.Var(newEnum(&groupBy, "package", "package", "file"), "group-by", "Group matches by package or by file. Packages without source are always grouped by package.")
+	flag.BoolVar(&resultErrorPairing, "result-error-pairing", false, "Only match functions returning (*T, error) whose package declares an error type named after T.")
+	flag.BoolVar(&forwardsResults, "forwards-results", false, "Only match functions that pass the results of a call directly to another call, as in f(g()). Requires source.")
+	flag.StringVar(&assignableSig, "assignable-sig", "", "Only match functions and method values assignable to this function type, such as net/http.HandlerFunc.")
+	flag.BoolVar(&outParams, "out-params", false, "Only match functions returning nothing or just an error that take a pointer parameter. -args then constrain the pointer's element type.")
+	flag.BoolVar(&hasBody, "has-body", false, "Only match functions implemented in Go. Requires source.")
+	flag.BoolVar(&externalOnly, "external-only", false, "Only match functions declared without a body, such as those implemented in assembly. Requires source.")
+	flag.BoolVar(&searchTypes, "types", false, "Search named types instead of functions, using -implements and -has-field.")
+	flag.Var(&implements, "implements", "In -types mode, comma-separated list of interfaces that types have to implement.")
+	flag.Var(&hasField, "has-field", "In -types mode, comma-separated list of types that types have to have fields of.")
+	flag.BoolVar(&duplicateArgs, "duplicate-args", false, "Only match functions taking two or more parameters of the same type, optionally restricted to -args.")
+	flag.Var(newEnum(&format, "text", "text", "json", "index"), "format", "Output format: text, json, or index to list matches per queried type.")
+	flag.BoolVar(&returnsPointer, "returns-pointer", false, "Only match functions returning a single pointer.")
+	flag.BoolVar(&returnsErrorOnly, "returns-error-only", false, "Only match functions returning just an error.")
+	flag.BoolVar(&returnsBool, "returns-bool", false, "Only match functions returning a single bool.")
+	flag.BoolVar(&returnsString, "returns-string", false, "Only match functions returning a single string.")
+	flag.BoolVar(&promoted, "promoted", false, "Also match methods that types get from their embedded fields.")
+	flag.BoolVar(&noPromotedStdlib, "no-promoted-stdlib", false, "Like -promoted, but exclude methods promoted from embedded standard library types.")
+	flag.BoolVar(&byConfidence, "by-confidence", false, "Order matches by how well they fit heuristic filters such as -constructors, best first.")
+	flag.BoolVar(&reachableFromExported, "reachable-from-exported", false, "Only match functions reachable from the exported API of their package. Requires source.")
+	flag.StringVar(&nargs, "nargs", "", "Only match functions with this many parameters, either a count such as 3 or a range such as 2..4.")
+	flag.StringVar(&nrets, "nrets", "", "Only match functions with this many results, either a count such as 1 or a range such as 1..2.")
+	flag.BoolVar(&deref, "deref", false, "Ignore a single level of pointer indirection when matching -args and -rets.")
+	flag.BoolVar(&positional, "positional", false, "Match -args and -rets by position, requiring exactly as many parameters and results. _ matches any type.")
+	flag.BoolVar(&anyChanDir, "any-chan-dir", false, "Let channel types such as chan int in -args and -rets also match send-only and receive-only channels.")
+	flag.BoolVar(&unqualified, "unqualified", false, "Compare the types of -args and -rets without their packages, so that Buffer matches bytes.Buffer. Combine with -deref to also match *bytes.Buffer.")
+	flag.BoolVar(&ignoreCase, "i", false, "Match the types of -args and -rets case-insensitively.")
+	flag.BoolVar(&regex, "regex", false, "Interpret the types of -args and -rets as regular expressions matching the whole type.")
+	flag.Var(newEnum(&kind, "any", "func", "method", "any"), "kind", "Only match free functions (func), methods (method) or both (any).")
+	flag.StringVar(&name, "name", "", "Only match functions whose name matches this glob, such as New*.")
+	flag.StringVar(&argName, "arg-name", "", "Only match functions with a parameter whose name matches this glob, such as ctx.")
+	flag.StringVar(&retName, "ret-name", "", "Only match functions with a named result whose name matches this glob, such as err.")
+	flag.BoolVar(&pos, "pos", false, "Print the file:line of each match's declaration, if known.")
+	flag.BoolVar(&doc, "doc", false, "Print the first line of each match's doc comment, if known.")
+	flag.StringVar(&tmpl, "template", "", "Print each match using this text/template, such as '{{.Package}} {{.Name}}'. See Record for the available fields.")
+	flag.IntVar(&limit, "limit", 0, "Only print the first this many matches, in the order of -sort, or by confidence with -by-confidence.")
+	flag.BoolVar(&count, "count", false, "Only print the number of matches per group, followed by the total.")
+	flag.BoolVar(&listMatchingPackages, "list-matching-packages", false, "Only print the paths of packages that contain matches.")
+	flag.Var(&buildTags, "tags", "Comma-separated list of build tags to consider satisfied when selecting files.")
+	flag.StringVar(&goos, "goos", "", "Select files for this GOOS instead of the host's.")
+	flag.StringVar(&goarch, "goarch", "", "Select files for this GOARCH instead of the host's.")
+	flag.BoolVar(&tests, "tests", false, "Also search the _test.go files of packages, including external test packages as path_test.")
+	flag.BoolVar(&jsonErrors, "json-errors", false, "With -format json, print an object with the matches and the errors and warnings of loading packages, instead of an array of matches.")
+	flag.BoolVar(&strictImport, "strict-import", false, "Fail if any package had to be imported from possibly stale gc generated data, and explain why importing it from source failed.")
+	flag.BoolVar(&noCache, "no-cache", false, "Don't cache type-checked packages. The cache is stored in $USES_CACHE, or in the user's cache directory.")
+	flag.StringVar(&gopath, "gopath", "", "Override GOPATH for finding packages. Entries are separated by the OS's list separator.")
+	flag.DurationVar(&timeout, "timeout", 0, "Stop loading packages after this long and fail.")
+	flag.BoolVar(&partial, "partial", false, "With -timeout, still print the matches found in the packages loaded before the timeout.")
+	flag.DurationVar(&deadline, "deadline", 0, "Stop loading packages after this long, warn about the skipped packages and print the matches found so far. -timeout takes precedence.")
+	flag.IntVar(&maxPackages, "max-packages", 0, "Load at most this many packages.")
+	flag.IntVar(&jobs, "j", runtime.GOMAXPROCS(0), "Number of packages to load concurrently.")
+	flag.BoolVar(&cleanVendor, "clean-vendor", true, "Strip vendor directory prefixes from type names.")
+	flag.Var(newEnum(&color, "auto", "auto", "always", "never"), "color", "Color output: auto, always or never. auto colors output only if stdout is a terminal and NO_COLOR is unset.")
+	flag.BoolVar(&verbose, "v", false, "Print informational and debug messages to stderr.")
+	flag.BoolVar(&quietOutput, "q", false, "Don't print matches; only report through the exit code whether there were any.")
+	flag.BoolVar(&quiet, "quiet", false, "Only print errors to stderr.")
+}
+
+func listErrors(errors []error) {
+	for _, err := range errors {
+		log.Errorf("%s", err)
+	}
+}
+
+// uniqueStrings removes duplicates from list, keeping the first
+// occurrence of each string.
+func uniqueStrings(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// flatLine is a line of output for the flat orders of -sort. line is
+// prefixed by key, the group the match would otherwise be printed in.
+type flatLine struct {
+	name, key, line string
+}
+
+// groupKey returns the group m is printed in: its file with -group-by
+// file, if known, and otherwise its Key. With -stable, files are named
+// by their package's import path.
+func groupKey(m uses.Match) string {
+	if groupBy == "file" && m.Pos.IsValid() {
+		if stable {
+			return path.Join(strings.TrimSuffix(m.Func.Pkg.Path(), "_test"), filepath.Base(m.Pos.Filename))
+		}
+		return m.Pos.Filename
+	}
+	return m.Key
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportFallbacks warns about the packages that had to be imported
+// from gc generated data. With -strict-import, it explains why
+// importing them from source failed instead, and returns false.
+func reportFallbacks(snapshot *uses.Snapshot) bool {
+	if len(snapshot.Fallbacks) == 0 {
+		return true
+	}
+	if strictImport {
+		log.Errorf("Couldn't import the following packages from source:")
+		for _, fb := range snapshot.ExplainFallbacks() {
+			if fb.Err != nil {
+				log.Errorf("%s: %s", fb.Path, fb.Err)
+			} else {
+				log.Errorf("%s: unknown error", fb.Path)
+			}
+		}
+		return false
+	}
+	log.Warnf("Relying on gc generated data for...")
+	for _, path := range snapshot.Fallbacks {
+		log.Warnf("%s", path)
+	}
+	log.Warnf("")
+	return true
+}
+
+// readPackageLists replaces the entries - and @file of -pkgs with the
+// packages listed, one per line, on stdin or in file. Empty lines and
+// lines starting with # are ignored.
+func readPackageLists(entries []string) ([]string, error) {
+	var out []string
+	for _, entry := range entries {
+		var r io.Reader
+		switch {
+		case entry == "-":
+			r = os.Stdin
+		case strings.HasPrefix(entry, "@"):
+			f, err := os.Open(entry[1:])
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		default:
+			out = append(out, entry)
+			continue
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			out = append(out, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("Couldn't read packages from %s: %s", entry, err)
+		}
+	}
+	return out, nil
+}
+
+// Exit codes, like grep's: a search exits with exitNoMatches if
+// nothing matched, and with exitError if there were errors, including
+// packages that couldn't be loaded.
+const (
+	exitSuccess   = 0
+	exitNoMatches = 1
+	exitError     = 2
+)
+
+// softDeadline reports whether loading is bounded by -deadline,
+// which, unlike -timeout, only warns about the packages it skipped.
+func softDeadline() bool {
+	return deadline > 0 && timeout == 0
+}
+
+// exitStatus returns the exit status of a run that found n results in
+// snapshot.
+func exitStatus(snapshot *uses.Snapshot, n int) int {
+	switch {
+	case len(snapshot.Errors) > 0, len(snapshot.Skipped) > 0 && !softDeadline():
+		return exitError
+	case n == 0:
+		return exitNoMatches
+	}
+	return exitSuccess
+}
+
+func main() {
+	flag.Parse()
+	if watch {
+		os.Exit(watchLoop())
+	}
+	os.Exit(run(context.Background()))
+}
+
+// run runs the query described by the flags, stopping to load
+// packages once parent is done.
+func run(parent context.Context) int {
+	switch {
+	case quiet:
+		log.level = levelError
+	case verbose:
+		log.level = levelDebug
+	default:
+		log.level = levelWarn
+	}
+
+	if describeOpts {
+		if err := describeOptions(); err != nil {
+			log.Errorf("%s", err)
+			return exitError
+		}
+		return exitSuccess
+	}
+
+	pkgs, err := readPackageLists(packages)
+	if err != nil {
+		log.Errorf("%s", err)
+		return exitError
+	}
+	if len(pkgs) == 0 {
+		log.Errorf("Need to specify at least one package to check.")
+		flag.Usage()
+		return exitError
+	}
+
+	q := uses.Query{
+		Args:                   append(arguments, argList...),
+		Rets:                   append(returns, retList...),
+		And:                    and,
+		MinMatches:             minMatches,
+		SameArg:                sameArg,
+		Assignable:             assignable || sameArg,
+		Underlying:             underlying,
+		AnyChanDir:             anyChanDir,
+		Unqualified:            unqualified,
+		ReturnsErrorType:       returnsErrorType,
+		ReturnsPtrImplementing: returnsPtrImplementing,
+		Constructors:           constructors,
+		ZeroArgConstructors:    zeroArgConstructors,
+		MinDeps:                minDeps,
+		UsesPkg:                usesPkg,
+		CatchAll:               catchAll,
+		ReturnsCleanup:         returnsCleanup,
+		Directive:              directive,
+		MinMethods:             minMethods,
+		AssignableTo:           assignableTo,
+		AssignableToAll:        assignableToMode == "all",
+		SelfConsistentReturns:  selfConsistentReturns,
+		VariadicOf:             variadicOf,
+		Variadic:               variadic,
+		Producers:              producers,
+		ProducerElem:           retElem,
+		StructByType:           structByType,
+		ContextNotFirst:        contextNotFirst,
+		ResultErrorPairing:     resultErrorPairing,
+		ForwardsResults:        forwardsResults,
+		AssignableSig:          assignableSig,
+		OutParams:              outParams,
+		HasBody:                hasBody,
+		ExternalOnly:           externalOnly,
+		ReachableFromExported:  reachableFromExported,
+		Positional:             positional,
+		Deref:                  deref,
+		Regex:                  regex,
+		IgnoreCase:             ignoreCase,
+		ExcludeTypes:           excludeTypes,
+		Name:                   name,
+		ArgName:                argName,
+		RetName:                retName,
+		DuplicateArgs:          duplicateArgs,
+		ReturnsPointer:         returnsPointer,
+		ReturnsErrorOnly:       returnsErrorOnly,
+		ReturnsBool:            returnsBool,
+		ReturnsString:          returnsString,
+		NoPromotedStdlib:       noPromotedStdlib,
+		Promoted:               promoted,
+	}
+	if nargs != "" {
+		r, err := uses.ParseRange(nargs)
+		if err != nil {
+			log.Errorf("-nargs: %s", err)
+			flag.Usage()
+			return exitError
+		}
+		q.NArgs = r
+	}
+	if nrets != "" {
+		r, err := uses.ParseRange(nrets)
+		if err != nil {
+			log.Errorf("-nrets: %s", err)
+			flag.Usage()
+			return exitError
+		}
+		q.NRets = r
+	}
+
+	switch returnsError {
+	case "last":
+		q.ReturnsError = uses.ErrorLast
+	case "any":
+		q.ReturnsError = uses.ErrorAny
+	case "none":
+		q.ReturnsError = uses.ErrorNone
+	}
+
+	switch kind {
+	case "func":
+		q.Kind = uses.KindFunc
+	case "method":
+		q.Kind = uses.KindMethod
+	}
+	if concreteArgs >= 0 {
+		q.ConcreteArgs = &concreteArgs
+	}
+	if interfaceArgs >= 0 {
+		q.InterfaceArgs = &interfaceArgs
+	}
+
+	var matchTemplate *template.Template
+	if tmpl != "" {
+		var err error
+		matchTemplate, err = template.New("match").Parse(tmpl)
+		if err != nil {
+			log.Errorf("Invalid template: %s", err)
+			return exitError
+		}
+	}
+
+	if stable && sortBy == "none" {
+		log.Errorf("-stable can't be combined with -sort none.")
+		flag.Usage()
+		return exitError
+	}
+
+	if queryExpr != "" {
+		expr, err := uses.ParseExpr(queryExpr)
+		if err != nil {
+			log.Errorf("%s", err)
+			return exitError
+		}
+		if positional || sameArg || minMatches > 0 || nargs != "" || nrets != "" {
+			log.Errorf("-query can't be combined with -positional, -same-arg, -min-matches, -nargs or -nrets.")
+			flag.Usage()
+			return exitError
+		}
+		q.Expr = expr
+	}
+
+	if err := q.CompilePatterns(); err != nil {
+		log.Errorf("%s", err)
+		return exitError
+	}
+	if _, err := path.Match(name, ""); err != nil {
+		log.Errorf("Invalid name pattern %s: %s", name, err)
+		return exitError
+	}
+
+	if !q.HasCriteria() && !suggestInterfaces && !matchBlankImport && !fields && methodCoverage == "" && !overrides && !searchTypes {
+		log.Errorf("Need at least one type to search for.")
+		flag.Usage()
+		return exitError
+	}
+
+	ctx := watchContext
+	if ctx == nil {
+		ctx = uses.NewContext()
+		ctx.Log = log
+		if gopath != "" {
+			ctx.Build.GOPATH = gopath
+		}
+		if goos != "" {
+			ctx.Build.GOOS = goos
+		}
+		if goarch != "" {
+			ctx.Build.GOARCH = goarch
+		}
+		ctx.Build.BuildTags = append(ctx.Build.BuildTags, buildTags...)
+		ctx.MaxPackages = maxPackages
+		ctx.Jobs = jobs
+		ctx.Tests = tests
+		// Packages read from the cache lack their source, and with
+		// it positions and docs, so only use it if nothing needs
+		// them. JSON and templates always include them.
+		if !noCache && !q.NeedsSource() && !pos && !doc && !tests && groupBy != "file" &&
+			!suggestInterfaces && !matchBlankImport && format != "json" && tmpl == "" {
+			ctx.CacheDir = uses.DefaultCacheDir()
+		}
+		ctx.ExcludePackages = excludePkgs
+		ctx.CleanVendor = cleanVendor
+		if wd, err := os.Getwd(); err == nil {
+			ctx.UseWorkspace(wd)
+		}
+		if watch {
+			watchContext = ctx
+		}
+	}
+	loadTimeout := timeout
+	if softDeadline() {
+		loadTimeout = deadline
+	}
+	ctx.Run = parent
+	if loadTimeout > 0 {
+		run, cancel := context.WithTimeout(parent, loadTimeout)
+		defer cancel()
+		ctx.Run = run
+	}
+	snapshot := uses.Load(ctx, ctx.ResolvePackages(pkgs))
+	watchedDirs = ctx.PackageDirs()
+	listErrors(snapshot.Errors)
+	if len(snapshot.Skipped) > 0 {
+		report := log.Errorf
+		switch {
+		case parent.Err() != nil:
+			log.Errorf("Interrupted: loaded %d packages, skipped %d:", len(snapshot.Loaded), len(snapshot.Skipped))
+		case softDeadline():
+			report = log.Warnf
+			log.Warnf("Reached the deadline of %s: loaded %d packages, skipped %d:", deadline, len(snapshot.Loaded), len(snapshot.Skipped))
+		default:
+			log.Errorf("Timed out after %s: loaded %d packages, skipped %d:", timeout, len(snapshot.Loaded), len(snapshot.Skipped))
+		}
+		for _, path := range snapshot.Skipped {
+			report("\t%s", path)
+		}
+		log.Infof("Loaded packages:")
+		for _, path := range snapshot.Loaded {
+			log.Infof("\t%s", path)
+		}
+		if !partial && (parent.Err() != nil || !softDeadline()) {
+			return exitError
+		}
+	}
+	if !reportFallbacks(snapshot) {
+		return exitError
+	}
+
+	if suggestInterfaces {
+		suggestions := snapshot.SuggestInterfaces(q.Args)
+		printSuggestions(suggestions)
+		return exitStatus(snapshot, len(suggestions))
+	}
+
+	if methodCoverage != "" {
+		cov, err := snapshot.MethodCoverage(methodCoverage)
+		if err != nil {
+			log.Errorf("%s", err)
+			return exitError
+		}
+		printCoverage(snapshot, cov)
+		return exitStatus(snapshot, len(cov.Types))
+	}
+
+	if searchTypes {
+		typs, err := snapshot.Types(uses.TypeQuery{Implements: implements, HasField: hasField})
+		if err != nil {
+			log.Errorf("%s", err)
+			return exitError
+		}
+		printTypes(snapshot, typs)
+		return exitStatus(snapshot, len(typs))
+	}
+
+	if overrides {
+		list := snapshot.Overrides()
+		printOverrides(list)
+		return exitStatus(snapshot, len(list))
+	}
+
+	if fields {
+		matches, err := snapshot.MatchFields(q, tag)
+		if err != nil {
+			log.Errorf("%s", err)
+			return exitError
+		}
+		printFields(matches)
+		return exitStatus(snapshot, len(matches))
+	}
+
+	if matchBlankImport {
+		importers := snapshot.BlankImports()
+		printBlankImports(importers)
+		return exitStatus(snapshot, len(importers))
+	}
+
+	matches, err := snapshot.Match(q)
+	if err != nil {
+		log.Errorf("%s", err)
+		return exitError
+	}
+
+	status := exitStatus(snapshot, len(matches))
+	if quietOutput {
+		return status
+	}
+
+	if limit > 0 && sortBy != "none" {
+		// Keep the matches that are printed first, independently of
+		// how packages were loaded, so that the same matches are kept
+		// on every run.
+		sort.SliceStable(matches, func(i, j int) bool {
+			ki, kj := groupKey(matches[i]), groupKey(matches[j])
+			ni, nj := matches[i].Func.Name(), matches[j].Func.Name()
+			if sortBy == "name" && ni != nj {
+				return ni < nj
+			}
+			if ki != kj {
+				return ki < kj
+			}
+			return ni < nj
+		})
+	}
+	if byConfidence {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Confidence > matches[j].Confidence
+		})
+	}
+	limited := limit > 0 && len(matches) > limit
+	if limited {
+		log.Infof("Only showing %d of %d matches", limit, len(matches))
+		matches = matches[:limit]
+	}
+
+	if listMatchingPackages {
+		paths := make(map[string][]string)
+		for _, m := range matches {
+			paths[m.Func.Pkg.Path()] = nil
+		}
+		for _, path := range sortedKeys(paths) {
+			fmt.Println(path)
+		}
+		return status
+	}
+
+	if shape {
+		printShapes(matches)
+		return status
+	}
+
+	if snippet {
+		printSnippets(matches)
+		return status
+	}
+
+	opts := uses.FormatOptions{Color: useColor(color), Position: pos}
+	if stable {
+		opts = uses.FormatOptions{OmitNames: true}
+	}
+
+	if matchTemplate != nil {
+		if err := printTemplate(matchTemplate, matches); err != nil {
+			log.Errorf("%s", err)
+			return exitError
+		}
+		return status
+	}
+
+	switch format {
+	case "index":
+		printIndex(q, matches, opts)
+		return status
+	case "json":
+		var err error
+		if jsonErrors {
+			err = printJSONErrors(matches, snapshot.Errors, snapshot.Fallbacks)
+		} else {
+			err = printJSON(matches)
+		}
+		if err != nil {
+			log.Errorf("%s", err)
+			return exitError
+		}
+		return status
+	}
+
+	var flat []flatLine
+	signatures := make(map[string][]string)
+	for _, m := range matches {
+		line := uses.FormatSignature(m, opts)
+		if m.Test {
+			line += " [test]"
+		}
+		if m.InterfaceMethod() {
+			line += " [interface]"
+		}
+		if from := m.PromotedFrom(); from != "" {
+			line += " [promoted from " + from + "]"
+		}
+		if m.Detail != "" {
+			line += " // " + m.Detail
+		}
+		if doc && m.Doc != "" && !stable {
+			line += "\n\t\t" + m.Doc
+		}
+		key := groupKey(m)
+		signatures[key] = append(signatures[key], line)
+		flat = append(flat, flatLine{m.Func.Name(), key, key + ": " + line})
+	}
+	for key, sigs := range signatures {
+		// The same method can be promoted through several
+		// embedded fields.
+		signatures[key] = uniqueStrings(sigs)
+	}
+	if groupBy == "file" || stable {
+		for _, sigs := range signatures {
+			sort.Strings(sigs)
+		}
+	}
+
+	if count {
+		total := 0
+		for _, key := range sortedKeys(signatures) {
+			fmt.Printf("%s %d\n", uses.FormatHeader(key, opts), len(signatures[key]))
+			total += len(signatures[key])
+		}
+		if limited {
+			fmt.Printf("total: %d (limited)\n", total)
+		} else {
+			fmt.Printf("total: %d\n", total)
+		}
+		return status
+	}
+
+	if sortBy != "package" {
+		if sortBy == "name" {
+			sort.SliceStable(flat, func(i, j int) bool {
+				if flat[i].name != flat[j].name {
+					return flat[i].name < flat[j].name
+				}
+				return flat[i].key < flat[j].key
+			})
+		}
+		lines := make([]string, len(flat))
+		for i, l := range flat {
+			lines[i] = l.line
+		}
+		for _, line := range uniqueStrings(lines) {
+			fmt.Println(line)
+		}
+		return status
+	}
+
+	for _, key := range sortedKeys(signatures) {
+		sigs := signatures[key]
+		fmt.Println(uses.FormatHeader(key, opts))
+		for _, sig := range sigs {
+			fmt.Println("\t" + sig)
+		}
+		fmt.Println()
+	}
+	return status
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"honnef.co/go/uses"
+
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// testdata is laid out as a GOPATH.
+	os.Setenv("GO111MODULE", "off")
+	os.Unsetenv("GOMODCACHE")
+	os.Unsetenv("GOWORK")
+	log.w = ioutil.Discard
+	os.Exit(m.Run())
+}
+
+// runArgs runs the command as if invoked with args, resetting all
+// other flags to their defaults, and returns what it printed to
+// stdout and its exit status. Packages are looked up in the
+// repository's testdata.
+func runArgs(t *testing.T, args ...string) (string, int) {
+	flag.VisitAll(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, "test.") {
+			return
+		}
+		switch v := f.Value.(type) {
+		case *stringSlice:
+			*v = nil
+		case *repeatedString:
+			*v = nil
+		default:
+			f.Value.Set(f.DefValue)
+		}
+	})
+	gopath, err := filepath.Abs(filepath.Join("..", "..", "testdata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args = append([]string{"-gopath", gopath, "-no-cache", "-color", "never"}, args...)
+	if err := flag.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	var code int
+	out := captureStdout(t, func() { code = run(context.Background()) })
+	return out, code
+}
+
+// captureStdout returns what f prints to os.Stdout.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestRepeatedArg(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{"-arg", "func(string, int) error"}, "functypes:\n\tWalk(fn func(string, int) error) (error)\n\n", exitSuccess},
+		{[]string{"-arg", "func(string) error", "-arg", "func(string, int) error"}, "functypes:\n\tVisit(fn func(string) error) (error)\n\tWalk(fn func(string, int) error) (error)\n\n", exitSuccess},
+		// -args splits the type at its comma.
+		{[]string{"-args", "func(string, int) error"}, "", exitNoMatches},
+	}
+	for _, tt := range tests {
+		out, code := runArgs(t, append([]string{"-pkgs", "functypes"}, tt.args...)...)
+		if code != tt.code {
+			t.Errorf("%q: got exit status %d, want %d", tt.args, code, tt.code)
+		}
+		if out != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.args, out, tt.want)
+		}
+	}
+}
+
+func TestMaxPackages(t *testing.T) {
+	tests := []struct {
+		pkgs string
+		out  string
+		code int
+	}{
+		{"errs,resolve", "errs\n", exitSuccess},
+		// errs is past the limit and isn't searched.
+		{"resolve,errs", "", exitNoMatches},
+	}
+	for _, tt := range tests {
+		out, code := runArgs(t, "-pkgs", tt.pkgs, "-max-packages", "1", "-rets", "error", "-list-matching-packages")
+		if out != tt.out || code != tt.code {
+			t.Errorf("%s: got %q and exit status %d, want %q and %d", tt.pkgs, out, code, tt.out, tt.code)
+		}
+	}
+}
+
+func TestReadPackageLists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	list := filepath.Join(dir, "pkgs.txt")
+	if err := ioutil.WriteFile(list, []byte("# generated\nerrs\n\n  resolve  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdin := filepath.Join(dir, "stdin.txt")
+	if err := ioutil.WriteFile(stdin, []byte("format\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	old := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = old }()
+
+	got, err := readPackageLists([]string{"single", "@" + list, "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"single", "errs", "resolve", "format"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := readPackageLists([]string{"@" + filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected an error for a missing list")
+	}
+}
+
+func TestReportFallbacks(t *testing.T) {
+	snapshot := uses.Load(uses.NewContext(), nil)
+	// fmt imports fine, so why it fell back can't be explained.
+	snapshot.Fallbacks = []string{"fmt", "nosuch"}
+	defer func(w io.Writer) { log.w = w }(log.w)
+
+	var buf bytes.Buffer
+	log.w = &buf
+	strictImport = false
+	if !reportFallbacks(snapshot) {
+		t.Error("fallbacks should only fail with -strict-import")
+	}
+	if got, want := buf.String(), "Relying on gc generated data for...\nfmt\nnosuch\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	strictImport = true
+	defer func() { strictImport = false }()
+	if reportFallbacks(snapshot) {
+		t.Error("fallbacks should fail with -strict-import")
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 3 || lines[1] != "fmt: unknown error" || !strings.HasPrefix(lines[2], "nosuch: ") {
+		t.Errorf("got %q", lines)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	defer func(w io.Writer) { log.w = w }(log.w)
+	tests := []struct {
+		flag string
+		code int
+		msg  string
+	}{
+		// -deadline gives up on the remaining packages with a
+		// warning, and doesn't fail.
+		{"-deadline", exitNoMatches, "Reached the deadline of 1ns: loaded 0 packages, skipped 1:\n\terrs\n"},
+		{"-timeout", exitError, "Timed out after 1ns: loaded 0 packages, skipped 1:\n\terrs\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		log.w = &buf
+		out, code := runArgs(t, "-pkgs", "errs", "-rets", "error", tt.flag, "1ns")
+		if out != "" || code != tt.code {
+			t.Errorf("%s: got %q and exit status %d, want no output and %d", tt.flag, out, code, tt.code)
+		}
+		if got := buf.String(); !strings.HasSuffix(got, tt.msg) {
+			t.Errorf("%s: logged %q, want it to end with %q", tt.flag, got, tt.msg)
+		}
+	}
+}
+
+func TestQueryIncompatible(t *testing.T) {
+	for _, args := range [][]string{
+		{"-positional"},
+		{"-same-arg"},
+		{"-min-matches", "1"},
+		{"-nargs", "1"},
+		{"-nrets", "0..1"},
+	} {
+		args = append([]string{"-pkgs", "errs", "-query", "ret:error"}, args...)
+		if out, code := runArgs(t, args...); out != "" || code != exitError {
+			t.Errorf("%v: got %q and exit status %d, want an error", args, out, code)
+		}
+	}
+}
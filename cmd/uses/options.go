@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// enumValue is a string flag that only accepts one of a fixed set of
+// values.
+type enumValue struct {
+	value  *string
+	def    string
+	values []string
+}
+
+// newEnum returns a flag storing into p, which it sets to def, and
+// accepting def and values.
+func newEnum(p *string, def string, values ...string) *enumValue {
+	*p = def
+	return &enumValue{p, def, values}
+}
+
+func (e *enumValue) String() string {
+	if e.value == nil {
+		return ""
+	}
+	return *e.value
+}
+
+func (e *enumValue) Set(val string) error {
+	if val == e.def {
+		*e.value = val
+		return nil
+	}
+	for _, v := range e.values {
+		if val == v {
+			*e.value = val
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(e.values, ", "))
+}
+
+func (e *enumValue) Get() interface{} { return *e.value }
+
+// flagTokens lists the special values, such as the pseudo-type _,
+// that flags accept in addition to their regular values.
+var flagTokens = make(map[string][]string)
+
+// withTokens records that the flag name accepts tokens and returns
+// name, for use in the flag's declaration.
+func withTokens(name string, tokens ...string) string {
+	flagTokens[name] = tokens
+	return name
+}
+
+type optionSchema struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Default string   `json:"default"`
+	Usage   string   `json:"usage"`
+	Values  []string `json:"values,omitempty"`
+	Tokens  []string `json:"tokens,omitempty"`
+}
+
+func flagType(f *flag.Flag) string {
+	switch f.Value.(type) {
+	case *stringSlice:
+		return "list"
+	case *repeatedString:
+		return "repeated"
+	case *enumValue:
+		return "enum"
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return "string"
+	}
+	switch getter.Get().(type) {
+	case bool:
+		return "bool"
+	case time.Duration:
+		return "duration"
+	case int, int64, uint, uint64:
+		return "int"
+	case float64:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+// describeOptions writes a JSON description of all flags to stdout,
+// for use by editor integrations.
+func describeOptions() error {
+	var options []optionSchema
+	flag.VisitAll(func(f *flag.Flag) {
+		opt := optionSchema{
+			Name:    f.Name,
+			Type:    flagType(f),
+			Default: f.DefValue,
+			Usage:   f.Usage,
+			Tokens:  flagTokens[f.Name],
+		}
+		if enum, ok := f.Value.(*enumValue); ok {
+			opt.Values = enum.values
+		}
+		options = append(options, opt)
+	})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(options)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"honnef.co/go/uses"
+
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestDescribeOptions(t *testing.T) {
+	var err error
+	out := captureStdout(t, func() { err = describeOptions() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var options []optionSchema
+	if err := json.Unmarshal([]byte(out), &options); err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]optionSchema)
+	for _, opt := range options {
+		byName[opt.Name] = opt
+	}
+
+	tests := []struct {
+		name   string
+		typ    string
+		values []string
+		tokens []string
+	}{
+		{"args", "list", nil, []string{uses.AnyType}},
+		{"arg", "repeated", nil, []string{uses.AnyType}},
+		{"pkgs", "list", nil, []string{"-", "@file"}},
+		{"assignable", "bool", nil, nil},
+		{"deadline", "duration", nil, nil},
+		{"format", "enum", []string{"text", "json", "index"}, nil},
+		{"sort", "enum", []string{"package", "name", "none"}, nil},
+	}
+	for _, tt := range tests {
+		opt, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("no option -%s", tt.name)
+			continue
+		}
+		if opt.Type != tt.typ {
+			t.Errorf("-%s has type %q, want %q", tt.name, opt.Type, tt.typ)
+		}
+		if !reflect.DeepEqual(opt.Values, tt.values) {
+			t.Errorf("-%s has values %v, want %v", tt.name, opt.Values, tt.values)
+		}
+		if !reflect.DeepEqual(opt.Tokens, tt.tokens) {
+			t.Errorf("-%s has tokens %v, want %v", tt.name, opt.Tokens, tt.tokens)
+		}
+	}
+}
+
+func TestEnumValue(t *testing.T) {
+	var s string
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Var(newEnum(&s, "", "a", "b"), "e", "")
+	if s != "" {
+		t.Errorf("default is %q, want empty", s)
+	}
+	if err := fs.Parse([]string{"-e", "b"}); err != nil || s != "b" {
+		t.Errorf("-e b: got %q, %v, want b", s, err)
+	}
+	if err := fs.Set("e", ""); err != nil || s != "" {
+		t.Errorf("resetting to the default: got %q, %v", s, err)
+	}
+	if err := fs.Parse([]string{"-e", "c"}); err == nil {
+		t.Errorf("-e c succeeded, want an error")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"honnef.co/go/uses"
+
+	"golang.org/x/tools/go/types"
+
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+func printBlankImports(importers map[string][]string) {
+	for _, path := range sortedKeys(importers) {
+		fmt.Println(path + ":")
+		for _, importer := range importers[path] {
+			fmt.Println("\t" + importer)
+		}
+		fmt.Println()
+	}
+}
+
+func printFields(matches map[string][]uses.FieldMatch) {
+	paths := make(map[string][]string)
+	for path, list := range matches {
+		for _, m := range list {
+			paths[path] = append(paths[path], m.String())
+		}
+	}
+	for _, path := range sortedKeys(paths) {
+		fmt.Println(path + ":")
+		for _, line := range paths[path] {
+			fmt.Println("\t" + line)
+		}
+		fmt.Println()
+	}
+}
+
+func printCoverage(snapshot *uses.Snapshot, cov *uses.Coverage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := []string{""}
+	for _, method := range cov.Methods {
+		header = append(header, method.Name())
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for i, tn := range cov.Types {
+		row := []string{snapshot.TypeString(tn.Type())}
+		for _, ok := range cov.Implements[i] {
+			if ok {
+				row = append(row, "x")
+			} else {
+				row = append(row, "-")
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+func printOverrides(overrides map[string][]uses.Override) {
+	lines := make(map[string][]string)
+	for path, list := range overrides {
+		for _, o := range list {
+			lines[path] = append(lines[path], o.String())
+		}
+	}
+	for _, path := range sortedKeys(lines) {
+		fmt.Println(path + ":")
+		for _, line := range lines[path] {
+			fmt.Println("\t" + line)
+		}
+		fmt.Println()
+	}
+}
+
+func printSuggestions(suggestions map[string][]uses.Suggestion) {
+	paths := make([]string, 0, len(suggestions))
+	for path := range suggestions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		fmt.Println(path + ":")
+		for _, s := range suggestions[path] {
+			fmt.Println("\t" + s.String())
+		}
+		fmt.Println()
+	}
+}
+
+func printTypes(snapshot *uses.Snapshot, matches []uses.TypeMatch) {
+	lines := make(map[string][]string)
+	for _, m := range matches {
+		path := m.Named.Obj().Pkg().Path()
+		line := m.Named.Obj().Name()
+		if m.PointerOnly {
+			line += " (via pointer)"
+		}
+		for _, field := range m.Fields {
+			line += fmt.Sprintf("\n\t\t%s %s", field.Name(), snapshot.TypeString(field.Type()))
+		}
+		lines[path] = append(lines[path], line)
+	}
+	for _, path := range sortedKeys(lines) {
+		fmt.Println(path + ":")
+		for _, line := range lines[path] {
+			fmt.Println("\t" + line)
+		}
+		fmt.Println()
+	}
+}
+
+// printJSON prints matches as a JSON array, sorted by package and
+// then by name.
+func printJSON(matches []uses.Match) error {
+	return encodeJSON(newRecords(matches))
+}
+
+// printJSONErrors prints a jsonOutput of matches, the errors errs
+// and warnings about the packages in fallbacks.
+func printJSONErrors(matches []uses.Match, errs []error, fallbacks []string) error {
+	return encodeJSON(jsonOutput{newRecords(matches), newJSONErrors(errs, fallbacks)})
+}
+
+// jsonOutput is the document printed by -format json -json-errors.
+type jsonOutput struct {
+	Matches []uses.Record `json:"matches"`
+	Errors  []jsonError   `json:"errors"`
+}
+
+// jsonError is an error or, for packages imported from gc generated
+// data, a warning about loading a package.
+type jsonError struct {
+	Package  string `json:"package,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Severity string `json:"severity"`
+	Error    string `json:"error"`
+}
+
+func newJSONErrors(errs []error, fallbacks []string) []jsonError {
+	out := make([]jsonError, 0, len(errs)+len(fallbacks))
+	for _, err := range errs {
+		if lerr, ok := err.(*uses.LoadError); ok {
+			out = append(out, jsonError{lerr.Path, lerr.Kind.String(), "error", lerr.Err.Error()})
+		} else {
+			out = append(out, jsonError{Severity: "error", Error: err.Error()})
+		}
+	}
+	for _, path := range fallbacks {
+		out = append(out, jsonError{path, "fallback", "warning", "imported from possibly stale gc generated data"})
+	}
+	return out
+}
+
+// newRecords returns the records of matches, sorted by package and
+// then by name.
+func newRecords(matches []uses.Match) []uses.Record {
+	records := make([]uses.Record, len(matches))
+	for i, m := range matches {
+		records[i] = m.Record()
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Package != records[j].Package {
+			return records[i].Package < records[j].Package
+		}
+		return records[i].Name < records[j].Name
+	})
+	return records
+}
+
+func encodeJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(v)
+}
+
+// printTemplate executes tmpl for the record of each match, printing
+// each on its own line.
+func printTemplate(tmpl *template.Template, matches []uses.Match) error {
+	for _, m := range matches {
+		if err := tmpl.Execute(os.Stdout, m.Record()); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// printSnippets prints the Snippet of each match, preceded by its
+// signature as a comment.
+func printSnippets(matches []uses.Match) {
+	for i, m := range matches {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println("// " + uses.FormatSignature(m, uses.FormatOptions{}))
+		fmt.Println(uses.Snippet(m))
+	}
+}
+
+// printShapes prints each distinct shape among matches, most frequent
+// first, followed by the functions that have it.
+func printShapes(matches []uses.Match) {
+	shapes := make(map[string][]string)
+	for _, m := range matches {
+		key := m.Shape()
+		shapes[key] = append(shapes[key], m.Func.Pkg.Path()+": "+uses.FormatSignature(m, uses.FormatOptions{}))
+	}
+
+	keys := sortedKeys(shapes)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return len(shapes[keys[i]]) > len(shapes[keys[j]])
+	})
+	for _, key := range keys {
+		fmt.Printf("%s (%d):\n", key, len(shapes[key]))
+		for _, fn := range shapes[key] {
+			fmt.Println("\t" + fn)
+		}
+		fmt.Println()
+	}
+}
+
+// printIndex prints, for each of the query's types, the matches that
+// use it: as a parameter for q.Args and the arg: terms of q.Expr, and
+// as a result for q.Rets and the ret: terms. Like when matching, the
+// final parameter of variadic functions is also indexed by its
+// element type.
+func printIndex(q uses.Query, matches []uses.Match, opts uses.FormatOptions) {
+	index := make(map[string][]string)
+	add := func(targets []string, params bool) {
+		for _, target := range targets {
+			for _, m := range matches {
+				matched := m.Matcher([]string{target})
+				t := m.Sig.Results()
+				if params {
+					t = m.Sig.Params()
+				}
+				for i := 0; i < t.Len(); i++ {
+					typ := t.At(i).Type()
+					variadic := params && m.Sig.Variadic() && i == t.Len()-1
+					if matched(typ) || (variadic && matched(typ.(*types.Slice).Elem())) {
+						index[target] = append(index[target], m.Func.Pkg.Path()+": "+uses.FormatSignature(m, opts))
+						break
+					}
+				}
+			}
+		}
+	}
+	args, rets := q.Terms()
+	add(args, true)
+	add(rets, false)
+
+	for _, typ := range sortedKeys(index) {
+		fns := index[typ]
+		sort.Strings(fns)
+		fmt.Println(typ + ":")
+		for i, fn := range fns {
+			if i > 0 && fn == fns[i-1] {
+				// Used as both parameter and result
+				continue
+			}
+			fmt.Println("\t" + fn)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	out, code := runArgs(t, "-pkgs", "resolve", "-rets", "map[string]int", "-format", "json")
+	if code != exitSuccess {
+		t.Fatalf("got exit status %d", code)
+	}
+	// Without -json-errors, the output is an array of matches.
+	var records []struct {
+		Package string `json:"package"`
+		Name    string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	if len(records) != 1 || records[0].Package != "resolve" || records[0].Name != "Map" {
+		t.Errorf("got %+v, want resolve.Map", records)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	out, _ := runArgs(t, "-pkgs", "resolve,broken,missing", "-rets", "map[string]int", "-format", "json", "-json-errors")
+	var doc jsonOutput
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	if len(doc.Matches) != 1 {
+		t.Errorf("got %d matches, want 1", len(doc.Matches))
+	}
+	kinds := make(map[string]jsonError)
+	for _, e := range doc.Errors {
+		kinds[e.Package] = e
+	}
+	for _, want := range []jsonError{
+		{Package: "broken", Kind: "parse", Severity: "error"},
+		{Package: "missing", Kind: "import", Severity: "error"},
+	} {
+		got, ok := kinds[want.Package]
+		if !ok {
+			t.Errorf("no error for %s: %+v", want.Package, doc.Errors)
+			continue
+		}
+		if got.Kind != want.Kind || got.Severity != want.Severity || got.Error == "" {
+			t.Errorf("got %+v, want kind %s and severity %s", got, want.Kind, want.Severity)
+		}
+	}
+	if e := kinds["broken"]; strings.HasPrefix(e.Error, "could not parse") {
+		t.Errorf("parse error repeats its kind: %q", e.Error)
+	}
+}
+
+func TestDoc(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"-v"}, false},
+		{[]string{"-doc"}, true},
+	}
+	for _, tt := range tests {
+		args := append([]string{"-pkgs", "resolve", "-rets", "map[string]int"}, tt.args...)
+		out, code := runArgs(t, args...)
+		if code != exitSuccess {
+			t.Fatalf("%v: got exit status %d", tt.args, code)
+		}
+		if got := strings.Contains(out, "Map returns a map."); got != tt.want {
+			t.Errorf("%v: doc printed = %t, want %t:\n%s", tt.args, got, tt.want, out)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		// Local type names are scoped to the package declaring them.
+		{[]string{"-rets", "*local"}, "*local:\n\tresolve: Local() (*resolve.local)\n\n"},
+		// Types are resolved for -underlying.
+		{[]string{"-rets", "handler", "-underlying"}, "handler:\n\tresolve: Func() (func(io.Reader) error)\n\tresolve: Handler() (resolve.handler)\n\n"},
+		// The terms of -query are indexed like -args and -rets.
+		{[]string{"-query", "ret:*local | ret:map[string]int"}, "*local:\n\tresolve: Local() (*resolve.local)\n\nmap[string]int:\n\tresolve: Map() (map[string]int)\n\n"},
+		// Variadic parameters are indexed by their element type.
+		{[]string{"-args", "int"}, "int:\n\tvariadic: Sum(ns []int) (int)\n\n"},
+	}
+	for _, tt := range tests {
+		args := append([]string{"-pkgs", "resolve,variadic", "-format", "index"}, tt.args...)
+		out, code := runArgs(t, args...)
+		if code != exitSuccess {
+			t.Fatalf("%v: got exit status %d", tt.args, code)
+		}
+		if out != tt.want {
+			t.Errorf("%v: got\n%s\nwant\n%s", tt.args, out, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	out, code := runArgs(t, "-pkgs", "resolve", "-rets", "*local", "-color", "always")
+	if code != exitSuccess {
+		t.Fatalf("got exit status %d", code)
+	}
+	// *local only matched as scoped to its package.
+	if want := "\x1b[32m*resolve.local\x1b[0m"; !strings.Contains(out, want) {
+		t.Errorf("got %q, want it to contain %q", out, want)
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		args    []string
+		escapes bool
+	}{
+		{[]string{"-color", "always"}, true},
+		{[]string{"-color", "never"}, false},
+		// Output is captured by a pipe, not a terminal.
+		{[]string{"-color", "auto"}, false},
+		{[]string{"-color", "always", "-format", "json"}, false},
+	}
+	for _, tt := range tests {
+		out, code := runArgs(t, append([]string{"-pkgs", "resolve", "-rets", "map[string]int"}, tt.args...)...)
+		if code != exitSuccess {
+			t.Fatalf("%q: got exit status %d", tt.args, code)
+		}
+		if got := strings.Contains(out, "\x1b["); got != tt.escapes {
+			t.Errorf("%q: got escapes %t, want %t: %q", tt.args, got, tt.escapes, out)
+		}
+	}
+}
+
+func TestLimitSort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"package", "platform/a:\n\tF() (platform/b.T)\n\nresolve:\n\tArray() ([4]byte)\n\n"},
+		{"name", "resolve: Array() ([4]byte)\nresolve: Chan() (chan int)\n"},
+	}
+	for _, tt := range tests {
+		out, code := runArgs(t, "-pkgs", "platform/a,resolve", "-rets", "_", "-limit", "2", "-sort", tt.sort)
+		if code != exitSuccess {
+			t.Fatalf("-sort %s: got exit status %d", tt.sort, code)
+		}
+		if out != tt.want {
+			t.Errorf("-sort %s: got\n%s\nwant\n%s", tt.sort, out, tt.want)
+		}
+	}
+
+	// Without sorting, the first matches found are kept.
+	all, _ := runArgs(t, "-pkgs", "resolve", "-rets", "_", "-sort", "none")
+	out, _ := runArgs(t, "-pkgs", "resolve", "-rets", "_", "-sort", "none", "-limit", "3")
+	if lines := strings.SplitAfter(all, "\n"); out != strings.Join(lines[:3], "") {
+		t.Errorf("-sort none -limit 3: got\n%s\nwant the first 3 lines of\n%s", out, all)
+	}
+}
+
+func TestReportExitStatus(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"-pkgs", "resolve", "-rets", "uint8", "-count"}, exitNoMatches},
+		{[]string{"-pkgs", "resolve", "-rets", "uint8", "-format", "index"}, exitNoMatches},
+		{[]string{"-pkgs", "resolve", "-rets", "uint8", "-list-matching-packages"}, exitNoMatches},
+		{[]string{"-pkgs", "resolve", "-rets", "map[string]int", "-count"}, exitSuccess},
+		{[]string{"-pkgs", "resolve", "-method-coverage", "io.Closer"}, exitNoMatches},
+		{[]string{"-pkgs", "example.org/promoted/inner", "-method-coverage", "io.Closer"}, exitSuccess},
+		{[]string{"-pkgs", "resolve", "-types", "-implements", "io.Closer"}, exitNoMatches},
+		{[]string{"-pkgs", "example.org/promoted/inner", "-types", "-implements", "io.Closer"}, exitSuccess},
+		{[]string{"-pkgs", "resolve", "-overrides"}, exitNoMatches},
+		{[]string{"-pkgs", "resolve", "-fields", "-args", "string"}, exitNoMatches},
+		{[]string{"-pkgs", "example.org/promoted/outer", "-fields", "-args", "sync.Mutex"}, exitSuccess},
+	}
+	for _, tt := range tests {
+		if _, code := runArgs(t, tt.args...); code != tt.want {
+			t.Errorf("%v: got exit status %d, want %d", tt.args, code, tt.want)
+		}
+	}
+}
+
+func TestListMatchingPackages(t *testing.T) {
+	out, code := runArgs(t, "-pkgs", "resolve,errs,deps", "-rets", "error", "-list-matching-packages")
+	if code != exitSuccess {
+		t.Fatalf("got exit status %d", code)
+	}
+	if want := "deps\nerrs\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestShapes(t *testing.T) {
+	out, code := runArgs(t, "-pkgs", "shapes", "-rets", "error", "-shape")
+	if code != exitSuccess {
+		t.Fatalf("got exit status %d", code)
+	}
+	// Functions share a shape regardless of the names of their
+	// parameters and results and of their receivers. Shapes are
+	// ordered by how many functions have them.
+	want := `func(string, int) (error) (3):
+	shapes: Create(path string, mode int) (err error)
+	shapes: (shapes.File) Write(s string, n int) (error)
+	shapes: Open(name string, flag int) (error)
+
+func() (error) (2):
+	shapes: Close() (error)
+	shapes: Reset() (error)
+
+func(string) (int, error) (1):
+	shapes: Count(s string) (int, error)
+
+`
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestGroupByFile(t *testing.T) {
+	out, code := runArgs(t, "-pkgs", "multifile,errs", "-rets", "error", "-group-by", "file")
+	if code != exitSuccess {
+		t.Fatalf("got exit status %d", code)
+	}
+	src, err := filepath.Abs(filepath.Join("..", "..", "testdata", "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files are sorted, and so are the functions in each file.
+	want := filepath.Join(src, "errs", "errs.go") + ":\n\tPlain() (error)\n\n" +
+		filepath.Join(src, "multifile", "a.go") + ":\n\tOpen() (error)\n\n" +
+		filepath.Join(src, "multifile", "b.go") + ":\n\tClose() (error)\n\tWrite() (error)\n\n"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+
+	// -stable doesn't depend on where the sources are.
+	out, code = runArgs(t, "-pkgs", "multifile,errs", "-rets", "error", "-group-by", "file", "-stable")
+	if code != exitSuccess {
+		t.Fatalf("-stable: got exit status %d", code)
+	}
+	want = "errs/errs.go:\n\tPlain() (error)\n\n" +
+		"multifile/a.go:\n\tOpen() (error)\n\n" +
+		"multifile/b.go:\n\tClose() (error)\n\tWrite() (error)\n\n"
+	if out != want {
+		t.Errorf("-stable: got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestStable(t *testing.T) {
+	// -stable drops names, positions and docs, and sorts, so that
+	// the output doesn't depend on where the sources are or on how
+	// they were written.
+	out, code := runArgs(t, "-pkgs", "names", "-rets", "error", "-stable", "-pos", "-doc", "-color", "always")
+	if code != exitSuccess {
+		t.Fatalf("got exit status %d", code)
+	}
+	want := "names:\n\tBlank(string) (error)\n\tClose(int) (error)\n\tOpen(string) (int, error)\n\tRename(string, string) (error)\n\n"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+
+	if _, code := runArgs(t, "-pkgs", "names", "-rets", "error", "-stable", "-sort", "none"); code != exitError {
+		t.Errorf("-stable with -sort none: got exit status %d, want %d", code, exitError)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		out  string
+		code int
+	}{
+		{"{{.Package}} {{.Name}}", "names Blank\nnames Close\nnames Open\nnames Rename\n", exitSuccess},
+		// Record has no such field.
+		{"{{.Nope}}", "", exitError},
+		{"{{.Name", "", exitError},
+	}
+	for _, tt := range tests {
+		out, code := runArgs(t, "-pkgs", "names", "-rets", "error", "-template", tt.tmpl)
+		if out != tt.out || code != tt.code {
+			t.Errorf("%s: got %q and exit status %d, want %q and %d", tt.tmpl, out, code, tt.out, tt.code)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	out, code := runArgs(t, "-pkgs", "names", "-name", "Open", "-pos")
+	if code != exitSuccess {
+		t.Fatalf("got exit status %d", code)
+	}
+	file, err := filepath.Abs(filepath.Join("..", "..", "testdata", "src", "names", "names.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "names:\n\tOpen(name string) (fd int, err error) " + file + ":3\n\n"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
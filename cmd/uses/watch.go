@@ -0,0 +1,111 @@
+package main
+
+import (
+	"honnef.co/go/uses"
+
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchInterval is how often -watch checks for changed files.
+const watchInterval = time.Second
+
+// watchedDirs is set by run to the directories of the packages it
+// loaded.
+var watchedDirs []string
+
+// watchContext is set by run in -watch mode to the Context it loaded
+// packages with, and reused by later runs, which only load the
+// packages that changed and those depending on them again.
+var watchContext *uses.Context
+
+// watchLoop calls run, and calls it again whenever a Go file in
+// watchedDirs is added, removed or modified, until interrupted.
+// Interrupting a run that is still loading packages cancels it.
+func watchLoop() int {
+	// Stdin can only be read once.
+	pkgs, err := readPackageLists(packages)
+	if err != nil {
+		log.Errorf("%s", err)
+		return exitError
+	}
+	packages = pkgs
+
+	interrupted, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	clear := isTerminal(os.Stdout)
+	for {
+		if clear {
+			fmt.Print("\x1b[H\x1b[2J")
+		}
+		watchedDirs = nil
+		status := run(interrupted)
+		if interrupted.Err() != nil {
+			return status
+		}
+		if len(watchedDirs) == 0 {
+			log.Errorf("No package directories to watch.")
+			return status
+		}
+		state := dirStates(watchedDirs)
+		var changed []string
+		for len(changed) == 0 {
+			select {
+			case <-interrupted.Done():
+				return status
+			case <-time.After(watchInterval):
+			}
+			changed = changedDirs(state, dirStates(watchedDirs))
+		}
+		watchContext.Invalidate(changed)
+	}
+}
+
+// dirStates summarizes the names, sizes and modification times of the
+// Go files in each of dirs, so that any change to them changes the
+// summary.
+func dirStates(dirs []string) map[string]string {
+	states := make(map[string]string)
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			states[dir] = err.Error()
+			continue
+		}
+		var state []string
+		for _, fi := range files {
+			if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") {
+				continue
+			}
+			state = append(state, fmt.Sprintf("%s %d %d", filepath.Join(dir, fi.Name()), fi.Size(), fi.ModTime().UnixNano()))
+		}
+		states[dir] = strings.Join(state, "\n")
+	}
+	return states
+}
+
+// changedDirs returns the directories whose states differ between
+// old and new.
+func changedDirs(old, new map[string]string) []string {
+	var changed []string
+	for dir, state := range new {
+		if prev, ok := old[dir]; !ok || prev != state {
+			changed = append(changed, dir)
+		}
+	}
+	return changed
+}
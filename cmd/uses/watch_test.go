@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChangedDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "uses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	for _, dir := range []string{a, b} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := dirStates([]string{a, b})
+
+	// Files other than Go files don't count.
+	if err := ioutil.WriteFile(filepath.Join(a, "README"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed := changedDirs(old, dirStates([]string{a, b})); len(changed) != 0 {
+		t.Errorf("changedDirs after adding README = %v, want none", changed)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(b, "b.go"), []byte("package b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, want := changedDirs(old, dirStates([]string{a, b})), []string{b}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changedDirs after adding b.go = %v, want %v", changed, want)
+	}
+}
@@ -0,0 +1,12 @@
+package uses
+
+const (
+	colorReset   = "\x1b[0m"
+	colorHeader  = "\x1b[1;34m"
+	colorName    = "\x1b[1m"
+	colorMatched = "\x1b[32m"
+)
+
+func colorize(s, color string) string {
+	return color + s + colorReset
+}
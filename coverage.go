@@ -0,0 +1,53 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+)
+
+// Coverage lists, for the methods of an interface, which types
+// implement which of them.
+type Coverage struct {
+	Methods []*types.Func
+	Types   []*types.TypeName
+	// Implements[i][j] reports whether Types[i] has an implementation
+	// of Methods[j].
+	Implements [][]bool
+}
+
+// MethodCoverage computes which of the searched types implement
+// which methods of the interface name. Types that implement none of
+// the methods are omitted.
+func (s *Snapshot) MethodCoverage(name string) (*Coverage, error) {
+	iface, err := s.lookupInterface(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cov := &Coverage{}
+	for i := 0; i < iface.NumMethods(); i++ {
+		cov.Methods = append(cov.Methods, iface.Method(i))
+	}
+
+	for _, named := range s.named {
+		tn := named.Obj()
+		if _, ok := tn.Type().Underlying().(*types.Interface); ok {
+			continue
+		}
+		mset := types.NewMethodSet(types.NewPointer(tn.Type()))
+		row := make([]bool, len(cov.Methods))
+		any := false
+		for j, method := range cov.Methods {
+			sel := mset.Lookup(method.Pkg(), method.Name())
+			if sel != nil && types.Identical(sel.Type(), method.Type()) {
+				row[j] = true
+				any = true
+			}
+		}
+		if any {
+			cov.Types = append(cov.Types, tn)
+			cov.Implements = append(cov.Implements, row)
+		}
+	}
+
+	return cov, nil
+}
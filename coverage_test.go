@@ -0,0 +1,33 @@
+package uses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMethodCoverage(t *testing.T) {
+	s := loadTest(t, "coverage")
+	cov, err := s.MethodCoverage("io.ReadCloser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var methods []string
+	for _, m := range cov.Methods {
+		methods = append(methods, m.Name())
+	}
+	if want := []string{"Close", "Read"}; !reflect.DeepEqual(methods, want) {
+		t.Fatalf("got methods %v, want %v", methods, want)
+	}
+	got := make(map[string][]bool)
+	for i, tn := range cov.Types {
+		got[tn.Name()] = cov.Implements[i]
+	}
+	want := map[string][]bool{
+		"Full":      {true, true},
+		"ReadOnly":  {false, true},
+		"CloseOnly": {true, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package uses
+
+import (
+	"fmt"
+)
+
+// ErrorKind categorizes the errors of loading packages.
+type ErrorKind int
+
+const (
+	// ImportError means that the package couldn't be found or
+	// imported.
+	ImportError ErrorKind = iota
+	// ParseError means that the package's files couldn't be read or
+	// parsed.
+	ParseError
+	// TypeError means that the package failed to type-check.
+	TypeError
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ImportError:
+		return "import"
+	case ParseError:
+		return "parse"
+	case TypeError:
+		return "type"
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// LoadError is an error that occurred while loading the package
+// Path. The errors of Snapshot are all LoadErrors.
+type LoadError struct {
+	Path string
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	switch e.Kind {
+	case ParseError:
+		return fmt.Sprintf("Couldn't parse %s: %s", e.Path, e.Err)
+	case TypeError:
+		return fmt.Sprintf("Couldn't type-check %s: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("Couldn't import %s: %s", e.Path, e.Err)
+}
@@ -0,0 +1,58 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+	"honnef.co/go/importer"
+
+	"go/ast"
+	"go/token"
+	"path/filepath"
+)
+
+// Fallback is a package that was imported from gc generated data,
+// which may be stale, because importing it from source failed.
+type Fallback struct {
+	Path string
+	// Err is why importing the package from source failed. It is nil
+	// if the failure couldn't be reproduced.
+	Err error
+}
+
+// ExplainFallbacks reports why each of s.Fallbacks couldn't be
+// imported from source, by importing it again without falling back
+// to gc generated data. Like loading, this finds the package with
+// the Build of the snapshot's Context.
+func (s *Snapshot) ExplainFallbacks() []Fallback {
+	fallbacks := make([]Fallback, len(s.Fallbacks))
+	for i, path := range s.Fallbacks {
+		fallbacks[i] = Fallback{Path: path, Err: s.ctx.checkSource(path)}
+	}
+	return fallbacks
+}
+
+// checkSource type-checks the package path from source the way
+// importLocked tries to, and returns why that fails.
+func (ctx *Context) checkSource(path string) error {
+	buildPkg, err := ctx.findPackage(path)
+	if err != nil {
+		return err
+	}
+	if len(buildPkg.CgoFiles) > 0 {
+		// Only the importer handles cgo.
+		_, err := importer.New().Import(make(map[string]*types.Package), path)
+		return err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, file := range buildPkg.GoFiles {
+		f, err := parseFile(fset, filepath.Join(buildPkg.Dir, file))
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	conf := ctx.depContext
+	conf.Import = ctx.importFrom(buildPkg.Dir)
+	_, err = conf.Check(path, fset, files, nil)
+	return err
+}
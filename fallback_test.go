@@ -0,0 +1,19 @@
+package uses
+
+import "testing"
+
+func TestExplainFallbacks(t *testing.T) {
+	s := loadTest(t, "errs")
+	// errs only exists in the Context's GOPATH, and broken doesn't
+	// parse.
+	s.Fallbacks = []string{"fmt", "errs", "nosuch", "broken"}
+	fallbacks := s.ExplainFallbacks()
+	if len(fallbacks) != len(s.Fallbacks) {
+		t.Fatalf("got %v", fallbacks)
+	}
+	for i, failed := range []bool{false, false, true, true} {
+		if fb := fallbacks[i]; fb.Path != s.Fallbacks[i] || (fb.Err != nil) != failed {
+			t.Errorf("%s: got %v", s.Fallbacks[i], fb)
+		}
+	}
+}
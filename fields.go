@@ -0,0 +1,87 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldMatch is a struct field that satisfied a field query.
+type FieldMatch struct {
+	Struct *types.TypeName
+	Field  *types.Var
+	Tag    string
+
+	keepVendor bool
+}
+
+func (m FieldMatch) String() string {
+	s := fmt.Sprintf("%s.%s %s", m.Struct.Name(), m.Field.Name(), typeString(m.Field.Type(), m.keepVendor))
+	if m.Tag != "" {
+		s += " `" + m.Tag + "`"
+	}
+	return s
+}
+
+// matchTag reports whether tag satisfies query, which is either a
+// key, matching any non-empty value, or key:value.
+func matchTag(tag reflect.StructTag, query string) bool {
+	index := strings.Index(query, ":")
+	if index == -1 {
+		return tag.Get(query) != ""
+	}
+	key, value := query[:index], query[index+1:]
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	return tag.Get(key) == value
+}
+
+// MatchFields returns the fields of named struct types whose type
+// matches one of q.Args and whose tag satisfies tag. Field types are
+// matched like the parameter types of functions, so -regex,
+// -assignable and the like apply. Empty q.Args or tag match
+// everything.
+func (s *Snapshot) MatchFields(q Query, tag string) (map[string][]FieldMatch, error) {
+	if err := q.CompilePatterns(); err != nil {
+		return nil, err
+	}
+	scoped := s.newScopedQueries(q)
+	matchers := make(map[string]func(types.Type) bool)
+	matches := make(map[string][]FieldMatch)
+	for _, named := range s.named {
+		tn := named.Obj()
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		matched, ok := matchers[tn.Pkg().Path()]
+		if !ok {
+			pq, ok := scoped.get(tn.Pkg())
+			if ok {
+				matched = pq.Matcher(pq.Args)
+			} else {
+				matched = func(types.Type) bool { return false }
+			}
+			matchers[tn.Pkg().Path()] = matched
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if len(q.Args) > 0 && !matched(field.Type()) {
+				continue
+			}
+			if tag != "" && !matchTag(reflect.StructTag(st.Tag(i)), tag) {
+				continue
+			}
+			path := tn.Pkg().Path()
+			matches[path] = append(matches[path], FieldMatch{tn, field, st.Tag(i), s.keepVendor})
+		}
+	}
+	if err := scoped.err(); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
@@ -0,0 +1,37 @@
+package uses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchFieldsTag(t *testing.T) {
+	s := loadTest(t, "tags")
+	tests := []struct {
+		args []string
+		tag  string
+		want []string
+	}{
+		{nil, "", []string{"Name", "Password", "Age", "Email", "internal"}},
+		{nil, "json", []string{"Name", "Password", "Age"}},
+		{nil, "json:-", []string{"Password"}},
+		{nil, `json:"-"`, []string{"Password"}},
+		{nil, "db", []string{"Age", "Email"}},
+		{nil, "db:email", []string{"Email"}},
+		{nil, "xml", nil},
+		{[]string{"string"}, "db", []string{"Email"}},
+	}
+	for _, tt := range tests {
+		matches, err := s.MatchFields(Query{Args: tt.args}, tt.tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range matches["tags"] {
+			got = append(got, m.Field.Name())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v %q: got %v, want %v", tt.args, tt.tag, got, tt.want)
+		}
+	}
+}
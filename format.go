@@ -0,0 +1,267 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Qualification controls how type names are qualified by their
+// package.
+type Qualification int
+
+const (
+	// QualifyPath qualifies types by their full import path, as in
+	// github.com/foo/bar.Thing.
+	QualifyPath Qualification = iota
+	// QualifyName qualifies types by their package name, as in
+	// bar.Thing.
+	QualifyName
+	// QualifyNone doesn't qualify types at all.
+	QualifyNone
+)
+
+// ReceiverStyle controls how the receivers of methods are rendered.
+type ReceiverStyle int
+
+const (
+	// ReceiverNamed renders the receiver's name and type: (r *T).
+	ReceiverNamed ReceiverStyle = iota
+	// ReceiverType renders only the receiver's type: (*T).
+	ReceiverType
+	// ReceiverNone omits the receiver.
+	ReceiverNone
+)
+
+// FormatOptions configure FormatSignature. The zero value produces
+// the tool's default output.
+type FormatOptions struct {
+	Qualification Qualification
+	// Variadic renders the final parameter of variadic functions as
+	// ...T instead of []T.
+	Variadic bool
+	Receiver ReceiverStyle
+	// Color highlights the function name, as well as the parameter
+	// and result types for which MatchedParam and MatchedResult
+	// return true, using ANSI escape sequences. If they are nil,
+	// FormatSignature highlights the types that matched the query's
+	// Args and Rets.
+	Color         bool
+	MatchedParam  func(types.Type) bool
+	MatchedResult func(types.Type) bool
+	// Position appends the file:line of the function's declaration,
+	// if known.
+	Position bool
+	// OmitNames leaves out the names of parameters and results, which
+	// packages imported from gc generated data may lack, so that a
+	// function renders the same however its package was loaded.
+	OmitNames bool
+}
+
+var qualifiedIdent = regexp.MustCompile(`([\w~-][\w.~/-]*)\.([\pL_][\pL\pN_]*)`)
+
+func (opts FormatOptions) qualify(s string) string {
+	switch opts.Qualification {
+	case QualifyName:
+		s = qualifiedIdent.ReplaceAllStringFunc(s, func(ident string) string {
+			index := strings.LastIndex(ident, ".")
+			return path.Base(ident[:index]) + ident[index:]
+		})
+	case QualifyNone:
+		s = qualifiedIdent.ReplaceAllString(s, "$2")
+	}
+	return s
+}
+
+// Param is a parameter, result or receiver of a matched function.
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	typ types.Type
+}
+
+func (p Param) String() string {
+	if p.Name == "" {
+		return p.Type
+	}
+	return p.Name + " " + p.Type
+}
+
+func newParam(v *types.Var, keepVendor bool) Param {
+	return Param{noDot(v.Name()), typeString(v.Type(), keepVendor), v.Type()}
+}
+
+func newParams(tuple *types.Tuple, keepVendor bool) []Param {
+	params := make([]Param, tuple.Len())
+	for i := range params {
+		params[i] = newParam(tuple.At(i), keepVendor)
+	}
+	return params
+}
+
+// Record is the structured description of a match that all output
+// formats are produced from.
+type Record struct {
+	Package  string  `json:"package"`
+	Name     string  `json:"name"`
+	Var      bool    `json:"var,omitempty"`
+	Receiver *Param  `json:"receiver,omitempty"`
+	Params   []Param `json:"params"`
+	Results  []Param `json:"results"`
+	Variadic bool    `json:"variadic,omitempty"`
+	// Matched is args, rets or both, depending on which of the
+	// query's types the function matched.
+	Matched    string  `json:"matched,omitempty"`
+	Detail     string  `json:"detail,omitempty"`
+	Confidence float64 `json:"confidence"`
+	// Position is the file:line of the function's declaration, if
+	// known.
+	Position string `json:"position,omitempty"`
+	// Doc is the first line of the function's doc comment, if known.
+	Doc string `json:"doc,omitempty"`
+	// Test is set for functions declared in _test.go files.
+	Test bool `json:"test,omitempty"`
+	// Interface is set for methods declared by interfaces.
+	Interface bool `json:"interface,omitempty"`
+	// Promoted is the receiver type of the declaration of a method
+	// promoted from an embedded field.
+	Promoted string `json:"promoted,omitempty"`
+}
+
+// Record returns the structured description of m.
+func (m Match) Record() Record {
+	rec := Record{
+		Package:    m.Func.Pkg.Path(),
+		Name:       m.Func.Name(),
+		Var:        m.Func.isVar(),
+		Params:     newParams(m.Sig.Params(), m.query.keepVendor),
+		Results:    newParams(m.Sig.Results(), m.query.keepVendor),
+		Variadic:   m.Sig.Variadic(),
+		Matched:    m.Via,
+		Detail:     m.Detail,
+		Confidence: m.Confidence,
+		Doc:        m.Doc,
+		Test:       m.Test,
+		Interface:  m.InterfaceMethod(),
+		Promoted:   m.PromotedFrom(),
+	}
+	if m.Pos.IsValid() {
+		rec.Position = fmt.Sprintf("%s:%d", m.Pos.Filename, m.Pos.Line)
+	}
+	if recv := m.Func.recv; recv != nil {
+		// Promoted methods are shown on the type they were found
+		// on.
+		rec.Receiver = &Param{"", m.query.typeString(recv), recv}
+	} else if recv := m.Sig.Recv(); recv != nil && !rec.Var {
+		p := newParam(recv, m.query.keepVendor)
+		rec.Receiver = &p
+	}
+	return rec
+}
+
+func (opts FormatOptions) tupleString(params []Param, variadic bool, matched func(types.Type) bool) string {
+	ret := make([]string, len(params))
+	for i, param := range params {
+		var typ string
+		if variadic && opts.Variadic && i == len(params)-1 {
+			typ = "..." + opts.qualify(strings.TrimPrefix(param.Type, "[]"))
+		} else {
+			typ = opts.qualify(param.Type)
+		}
+		if opts.Color && matched != nil && matched(param.typ) {
+			typ = colorize(typ, colorMatched)
+		}
+
+		if len(param.Name) == 0 || opts.OmitNames {
+			ret[i] = typ
+		} else {
+			ret[i] = param.Name + " " + typ
+		}
+	}
+
+	return strings.Join(ret, ", ")
+}
+
+// FormatSignature renders the signature of a matched function.
+func FormatSignature(m Match, opts FormatOptions) string {
+	if opts.Color {
+		if opts.MatchedParam == nil {
+			opts.MatchedParam = m.Matcher(m.query.Args)
+		}
+		if opts.MatchedResult == nil {
+			opts.MatchedResult = m.Matcher(m.query.Rets)
+		}
+	}
+	return opts.formatRecord(m.Record())
+}
+
+// FormatHeader renders the header of a group of matches, such as
+// their package.
+func FormatHeader(key string, opts FormatOptions) string {
+	header := key + ":"
+	if opts.Color {
+		header = colorize(header, colorHeader)
+	}
+	return header
+}
+
+func (opts FormatOptions) formatRecord(rec Record) string {
+	prefix := ""
+	if rec.Var {
+		prefix = "var "
+	} else if recv := rec.Receiver; recv != nil {
+		switch opts.Receiver {
+		case ReceiverNamed:
+			if recv.Name == "" || opts.OmitNames {
+				// Interface methods have no receiver name
+				prefix = fmt.Sprintf("(%s) ", opts.qualify(recv.Type))
+			} else {
+				prefix = fmt.Sprintf("(%s %s) ", recv.Name, opts.qualify(recv.Type))
+			}
+		case ReceiverType:
+			prefix = fmt.Sprintf("(%s) ", opts.qualify(recv.Type))
+		}
+	}
+
+	name := rec.Name
+	if opts.Color {
+		name = colorize(name, colorName)
+	}
+
+	sig := fmt.Sprintf("%s%s(%s) (%s)",
+		prefix,
+		name,
+		opts.tupleString(rec.Params, rec.Variadic, opts.MatchedParam),
+		opts.tupleString(rec.Results, false, opts.MatchedResult))
+	if opts.Position && rec.Position != "" {
+		sig += " " + rec.Position
+	}
+	return sig
+}
+
+// ShapeOf returns the shape of a signature: its parameter and
+// result types, without any names or the receiver.
+func ShapeOf(sig *types.Signature) string {
+	return shapeOf(sig, false)
+}
+
+// Shape is ShapeOf for the signature of m, honoring the CleanVendor
+// setting it was matched with.
+func (m Match) Shape() string {
+	return shapeOf(m.Sig, m.query.keepVendor)
+}
+
+func shapeOf(sig *types.Signature, keepVendor bool) string {
+	tuple := func(t *types.Tuple) string {
+		typs := make([]string, t.Len())
+		for i := range typs {
+			typs[i] = typeString(t.At(i).Type(), keepVendor)
+		}
+		return strings.Join(typs, ", ")
+	}
+	return fmt.Sprintf("func(%s) (%s)", tuple(sig.Params()), tuple(sig.Results()))
+}
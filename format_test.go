@@ -0,0 +1,65 @@
+package uses
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatSignature(t *testing.T) {
+	s := loadTest(t, "example.org/format")
+	matches, err := s.Match(Query{Args: []string{"*bytes.Buffer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %v, want Write", matchNames(matches))
+	}
+	m := matches[0]
+
+	tests := []struct {
+		opts FormatOptions
+		want string
+	}{
+		{FormatOptions{}, "(t *example.org/format.T) Write(b *bytes.Buffer, args []string) (n int, err error)"},
+		{FormatOptions{Qualification: QualifyName, Variadic: true}, "(t *format.T) Write(b *bytes.Buffer, args ...string) (n int, err error)"},
+		{FormatOptions{Qualification: QualifyNone, Receiver: ReceiverType}, "(*T) Write(b *Buffer, args []string) (n int, err error)"},
+		{FormatOptions{Receiver: ReceiverNone, OmitNames: true}, "Write(*bytes.Buffer, []string) (int, error)"},
+		{FormatOptions{Receiver: ReceiverNone, Color: true}, "\x1b[1mWrite\x1b[0m(b \x1b[32m*bytes.Buffer\x1b[0m, args []string) (n int, err error)"},
+	}
+	for _, tt := range tests {
+		if got := FormatSignature(m, tt.opts); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+
+	got := FormatSignature(m, FormatOptions{Receiver: ReceiverNone, Position: true})
+	if want := "format.go:7"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, want it to end in %q", got, want)
+	}
+}
+
+func TestShapeOf(t *testing.T) {
+	s := loadTest(t, "shapes")
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Open", "func(string, int) (error)"},
+		{"Create", "func(string, int) (error)"},
+		{"Write", "func(string, int) (error)"},
+		{"Close", "func() (error)"},
+		{"Count", "func(string) (int, error)"},
+	}
+	for _, tt := range tests {
+		matches, err := s.Match(Query{Name: tt.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 {
+			t.Fatalf("%s: got %v", tt.name, matchNames(matches))
+		}
+		if got := ShapeOf(matches[0].Sig); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/gcimporter"
+	"golang.org/x/tools/go/types"
+
+	"go/ast"
+	"go/build"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// importPackage imports path for the type checker. The importer and
+// allImports are shared by all packages being loaded, so imports are
+// serialized.
+func (ctx *Context) importPackage(imports map[string]*types.Package, path string) (*types.Package, error) {
+	ctx.importMu.Lock()
+	defer ctx.importMu.Unlock()
+	return ctx.importLocked(path)
+}
+
+// importFrom returns the Import function for the package in dir,
+// which resolves the package's imports of vendored packages.
+func (ctx *Context) importFrom(dir string) func(map[string]*types.Package, string) (*types.Package, error) {
+	return func(imports map[string]*types.Package, path string) (*types.Package, error) {
+		return ctx.importPackage(imports, ctx.vendored(path, dir))
+	}
+}
+
+// importNestedFrom is like importFrom, but for packages that are
+// themselves being imported, while importMu is already held.
+func (ctx *Context) importNestedFrom(dir string) func(map[string]*types.Package, string) (*types.Package, error) {
+	return func(imports map[string]*types.Package, path string) (*types.Package, error) {
+		return ctx.importLocked(ctx.vendored(path, dir))
+	}
+}
+
+// vendored returns the import path of the vendored copy of path that
+// a package in dir imports, the way GOPATH mode resolves vendor
+// directories, or path itself if there is none.
+func (ctx *Context) vendored(path, dir string) string {
+	if dir == "" || build.IsLocalImport(path) {
+		return path
+	}
+	for _, root := range ctx.Build.SrcDirs() {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		for {
+			vendor := filepath.Join(rel, "vendor", filepath.FromSlash(path))
+			if fi, err := os.Stat(filepath.Join(root, vendor)); err == nil && fi.IsDir() {
+				return filepath.ToSlash(vendor)
+			}
+			if rel == "." {
+				break
+			}
+			rel = filepath.Dir(rel)
+		}
+	}
+	return path
+}
+
+// importLocked imports path, finding it with ctx.Build, so that
+// imports honor its GOOS, GOARCH, build tags and GOPATH just like
+// the searched packages do. Packages are type-checked from source,
+// except for the standard library when building for the host, which
+// is read from gc generated data. If that fails, the importer gets a
+// chance, which falls back to gc generated data and records doing
+// so. importMu must be held.
+func (ctx *Context) importLocked(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if pkg, ok := ctx.allImports[path]; ok {
+		if pkg.Complete() {
+			return pkg, nil
+		}
+		// Only partially known from the gc generated data of
+		// another package.
+		return ctx.importer.Import(ctx.allImports, path)
+	}
+
+	buildPkg, err := ctx.findPackage(path)
+	if err != nil || len(buildPkg.CgoFiles) > 0 {
+		return ctx.importer.Import(ctx.allImports, path)
+	}
+	if buildPkg.Goroot && ctx.hostPlatform() {
+		return gcimporter.Import(ctx.allImports, path)
+	}
+	pkg, err := ctx.checkImport(path, buildPkg)
+	if err != nil {
+		ctx.Log.Debugf("Couldn't import %s from source: %s", path, err)
+		return ctx.importer.Import(ctx.allImports, path)
+	}
+	return pkg, nil
+}
+
+// checkImport type-checks the non-test files of buildPkg as the
+// package path and records it in allImports. importMu must be held.
+func (ctx *Context) checkImport(path string, buildPkg *build.Package) (*types.Package, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, file := range buildPkg.GoFiles {
+		f, err := parseFile(fset, filepath.Join(buildPkg.Dir, file))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := ctx.depContext
+	conf.Import = ctx.importNestedFrom(buildPkg.Dir)
+	pkg, err := conf.Check(path, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx.allImports[path] = pkg
+	ctx.importDirs[path] = buildPkg.Dir
+	return pkg, nil
+}
+
+// findPackage locates the package path with ctx.Build, preferring
+// the modules of the workspace, if any.
+func (ctx *Context) findPackage(path string) (*build.Package, error) {
+	if dir, ok := ctx.workspace.dir(path); ok {
+		buildPkg, err := ctx.Build.ImportDir(dir, 0)
+		if buildPkg != nil {
+			buildPkg.ImportPath = path
+		}
+		return buildPkg, err
+	}
+	return ctx.Build.Import(path, ".", 0)
+}
+
+// hostPlatform reports whether ctx.Build targets the platform and
+// GOROOT of the host, whose gc generated data describes the standard
+// library correctly.
+func (ctx *Context) hostPlatform() bool {
+	return ctx.Build.GOOS == build.Default.GOOS &&
+		ctx.Build.GOARCH == build.Default.GOARCH &&
+		ctx.Build.GOROOT == build.Default.GOROOT
+}
+
+// fallbacks returns the packages that the importer had to import
+// from gc generated data so far.
+func (ctx *Context) fallbacks() []string {
+	ctx.importMu.Lock()
+	defer ctx.importMu.Unlock()
+	return append([]string(nil), ctx.importer.Fallbacks...)
+}
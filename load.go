@@ -0,0 +1,897 @@
+// Package uses finds functions by the types of their parameters and
+// results. The uses command in cmd/uses is a thin wrapper around it.
+package uses
+
+import (
+	"golang.org/x/tools/go/gcimporter"
+	"golang.org/x/tools/go/types"
+	"honnef.co/go/importer"
+
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+func parseFile(fset *token.FileSet, fileName string) (f *ast.File, err error) {
+	return parser.ParseFile(fset, fileName, nil, parser.ParseComments)
+}
+
+type Type struct {
+	Object   types.Object
+	TypeName *types.TypeName
+	Pointer  *types.Pointer
+}
+
+// sourcePackage holds the parsed and type-checked source of a
+// package. It is only available for packages that weren't loaded
+// from gc generated data.
+type sourcePackage struct {
+	pkg   *types.Package
+	fset  *token.FileSet
+	files []*ast.File
+	info  *types.Info
+	decls map[types.Object]*ast.FuncDecl
+}
+
+func newSourcePackage(pkg *types.Package, fset *token.FileSet, files []*ast.File, info *types.Info) *sourcePackage {
+	decls := make(map[types.Object]*ast.FuncDecl)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				if obj := info.Defs[fn.Name]; obj != nil {
+					decls[obj] = fn
+				}
+			}
+		}
+	}
+
+	return &sourcePackage{pkg, fset, files, info, decls}
+}
+
+// findSource returns the source of the package declaring fnc and
+// fnc's object in it, if that package is among sources. For promoted
+// methods, that is the package of the embedded type rather than
+// fnc.Pkg.
+func findSource(sources map[string]*sourcePackage, fnc Func) (*sourcePackage, types.Object, bool) {
+	pkg := fnc.Object.Pkg()
+	if pkg == nil {
+		pkg = fnc.Pkg
+	}
+	src, ok := sources[pkg.Path()]
+	if !ok {
+		return nil, nil, false
+	}
+	if src.pkg == pkg {
+		return src, fnc.Object, true
+	}
+	// The declaring package was both searched and imported by the
+	// package embedding its type, which yields distinct copies of
+	// its types. Find the method in the searched copy.
+	obj := src.method(fnc.Object)
+	return src, obj, obj != nil
+}
+
+// method returns the method of the package's types with the same
+// receiver type name and name as fn, or nil.
+func (src *sourcePackage) method(fn types.Object) types.Object {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return nil
+	}
+	recv, ok := deref(sig.Recv().Type()).(*types.Named)
+	if !ok {
+		return nil
+	}
+	tn, ok := src.pkg.Scope().Lookup(recv.Obj().Name()).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		if m := named.Method(i); m.Name() == fn.Name() {
+			return m
+		}
+	}
+	if iface, ok := named.Underlying().(*types.Interface); ok {
+		for i := 0; i < iface.NumExplicitMethods(); i++ {
+			if m := iface.ExplicitMethod(i); m.Name() == fn.Name() {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// source is like findSource for the packages of the snapshot.
+func (s *Snapshot) source(fnc Func) (*sourcePackage, types.Object, bool) {
+	return findSource(s.sources, fnc)
+}
+
+// Position returns the position of the declaration of m's function,
+// which is only valid if the package declaring it was type-checked
+// from source.
+func (s *Snapshot) Position(m Match) token.Position {
+	return s.position(m.Func)
+}
+
+func (s *Snapshot) position(fnc Func) token.Position {
+	src, obj, ok := s.source(fnc)
+	if !ok {
+		return token.Position{}
+	}
+	return src.fset.Position(obj.Pos())
+}
+
+// funcDecl returns the declaration of fnc, or nil if the package
+// declaring it wasn't type-checked from source.
+func (s *Snapshot) funcDecl(fnc Func) *ast.FuncDecl {
+	src, obj, ok := s.source(fnc)
+	if !ok {
+		return nil
+	}
+	return src.decls[obj]
+}
+
+// docSummary returns the first line of fnc's doc comment, or the
+// empty string if fnc has none or its package wasn't type-checked
+// from source.
+func (s *Snapshot) docSummary(fnc Func) string {
+	decl := s.funcDecl(fnc)
+	if decl == nil || decl.Doc == nil {
+		return ""
+	}
+	doc := strings.TrimSpace(decl.Doc.Text())
+	if index := strings.Index(doc, "\n"); index != -1 {
+		doc = doc[:index]
+	}
+	return doc
+}
+
+// Logger receives the diagnostics of loading packages.
+type Logger interface {
+	Warnf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Debugf(string, ...interface{}) {}
+
+// Context loads packages and resolves the types named in queries.
+// Its exported fields may be changed before loading any packages.
+// Each package is only loaded once; see Invalidate.
+type Context struct {
+	// Build is used to find packages, expand patterns and select
+	// files, honoring its GOPATH, build tags, GOOS and GOARCH. This
+	// applies to the imports of the searched packages as well. It
+	// defaults to build.Default.
+	Build build.Context
+	// Run bounds the duration of loading. Once it is done, no
+	// further packages are loaded.
+	Run context.Context
+	// MaxPackages, if positive, limits the number of packages to
+	// load.
+	MaxPackages int
+	// Log receives diagnostics. By default they are discarded.
+	Log Logger
+	// ExcludePackages lists patterns, such as .../internal/..., of
+	// packages not to load.
+	ExcludePackages []string
+	// Tests additionally loads the _test.go files of packages
+	// type-checked from source, including external test packages,
+	// which are loaded as the package's path with a _test suffix.
+	Tests bool
+	// CacheDir, if not empty, caches the type information of
+	// packages type-checked from source in this directory, keyed by
+	// the state of their files. Packages read from the cache lack
+	// their source, like packages loaded from gc generated data, so
+	// filters that require source don't match them.
+	CacheDir string
+	// Jobs is the number of packages loaded concurrently. It
+	// defaults to GOMAXPROCS.
+	Jobs int
+	// CleanVendor strips vendor directory prefixes from type names,
+	// so that vendored types match and print like the packages they
+	// vendor. It defaults to true.
+	CleanVendor bool
+
+	// mu protects dirs and memo, which accumulate the results of all
+	// loads.
+	mu sync.Mutex
+	// importMu serializes uses of importer and allImports.
+	importMu   sync.Mutex
+	allImports map[string]*types.Package
+	// importDirs maps the imports type-checked from source to their
+	// directories. It is protected by importMu.
+	importDirs map[string]string
+	context    types.Config
+	// depContext type-checks the imports of packages from
+	// source.
+	depContext types.Config
+	importer   *importer.Importer
+	// dirs maps the packages that were searched, including those
+	// that failed to load or were skipped, to their directories.
+	dirs map[string]string
+	// memo holds the results of loading packages successfully, by
+	// the paths they were loaded as, until Invalidate drops them.
+	// It is protected by mu.
+	memo map[string][]loadResult
+	// depKeysMu protects depKeys, which caches the cache keys of
+	// imported packages. It is reset whenever packages are loaded.
+	depKeysMu sync.Mutex
+	depKeys   map[string]string
+	// workspace is the go.work workspace, if any.
+	workspace *workspace
+}
+
+func NewContext() *Context {
+	importer := importer.New()
+	importer.Config.UseGcFallback = true
+	ctx := &Context{
+		importer:    importer,
+		allImports:  importer.Imports,
+		Build:       build.Default,
+		Run:         context.Background(),
+		Log:         nopLogger{},
+		Jobs:        runtime.GOMAXPROCS(0),
+		CleanVendor: true,
+		dirs:        make(map[string]string),
+		memo:        make(map[string][]loadResult),
+		importDirs:  make(map[string]string),
+		depKeys:     make(map[string]string),
+	}
+
+	return ctx
+}
+
+func check(ctx *Context, name, dir string, fset *token.FileSet, astFiles []*ast.File, info *types.Info) (pkg *types.Package, err error) {
+	// go/types can panic on pathological input. Don't let a single
+	// bad package take down the whole run.
+	defer func() {
+		if r := recover(); r != nil {
+			ctx.Log.Debugf("Recovered from panic while checking %s: %v\n%s", name, r, debug.Stack())
+			pkg, err = nil, fmt.Errorf("type checker panicked: %v", r)
+		}
+	}()
+	conf := ctx.context
+	conf.Import = ctx.importFrom(dir)
+	return conf.Check(name, fset, astFiles, info)
+}
+
+// getObjects loads the packages matched by paths and returns their
+// package-level objects, the packages that were loaded, and the
+// packages that were skipped because ctx.Run was done.
+func (ctx *Context) getObjects(paths []string) (objects []types.Object, sources map[string]*sourcePackage, loaded, skipped []string, errors []error) {
+	ctx.depKeysMu.Lock()
+	ctx.depKeys = make(map[string]string)
+	ctx.depKeysMu.Unlock()
+
+	var expanded []string
+	for _, path := range paths {
+		expanded = append(expanded, ctx.expandPattern(path)...)
+	}
+	paths = expanded
+	for _, pattern := range ctx.ExcludePackages {
+		match := matchPattern(pattern)
+		var kept []string
+		for _, path := range paths {
+			if !match(path) {
+				kept = append(kept, path)
+			}
+		}
+		paths = kept
+	}
+	if ctx.MaxPackages > 0 && len(paths) > ctx.MaxPackages {
+		ctx.Log.Warnf("Reached the limit of %d packages, skipping the remaining %d", ctx.MaxPackages, len(paths)-ctx.MaxPackages)
+		paths = paths[:ctx.MaxPackages]
+	}
+
+	// Packages are loaded concurrently, but their results are
+	// collected in order, so that output doesn't depend on
+	// scheduling.
+	results := make([][]loadResult, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := ctx.Jobs
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = ctx.loadMemoized(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		if err := ctx.Run.Err(); err != nil {
+			ctx.Log.Warnf("Stopped loading packages (%s), skipping the remaining %d", err, len(paths)-i)
+			skipped = paths[i:]
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sources = make(map[string]*sourcePackage)
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for _, res := range results {
+		for _, res := range res {
+			errors = append(errors, res.errs...)
+			if res.dir != "" {
+				ctx.dirs[res.path] = res.dir
+			}
+			if res.pkg == nil {
+				continue
+			}
+			if res.src != nil {
+				sources[res.path] = res.src
+			}
+			loaded = append(loaded, res.path)
+			scope := res.pkg.Scope()
+			for _, n := range scope.Names() {
+				obj := scope.Lookup(n)
+				objects = append(objects, obj)
+			}
+		}
+	}
+
+	return objects, sources, loaded, skipped, errors
+}
+
+// loadMemoized is like loadPackage, but reuses the results of earlier
+// loads that succeeded.
+func (ctx *Context) loadMemoized(path string) []loadResult {
+	ctx.mu.Lock()
+	results, ok := ctx.memo[path]
+	ctx.mu.Unlock()
+	if ok {
+		ctx.Log.Debugf("Reusing %s", path)
+		return results
+	}
+	results = ctx.loadPackage(path)
+	if len(results) == 0 {
+		return results
+	}
+	for _, res := range results {
+		if res.pkg == nil {
+			return results
+		}
+	}
+	ctx.mu.Lock()
+	ctx.memo[path] = results
+	ctx.mu.Unlock()
+	return results
+}
+
+// Invalidate makes later loads read the packages in dirs again, as
+// well as the packages depending on them, after their files changed.
+// Otherwise, a Context loads each package only once.
+func (ctx *Context) Invalidate(dirs []string) {
+	changed := make(map[string]bool)
+	for _, dir := range dirs {
+		changed[filepath.Clean(dir)] = true
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.importMu.Lock()
+	defer ctx.importMu.Unlock()
+
+	stale := make(map[string]bool)
+	for path, dir := range ctx.importDirs {
+		if changed[filepath.Clean(dir)] {
+			stale[path] = true
+		}
+	}
+	staleMemo := make(map[string]bool)
+	imports := func(pkg *types.Package) bool {
+		for _, imp := range pkg.Imports() {
+			if stale[imp.Path()] {
+				return true
+			}
+		}
+		return false
+	}
+	// Packages depending on stale packages are stale as well.
+	for grew := true; grew; {
+		grew = false
+		for path, pkg := range ctx.allImports {
+			if !stale[path] && imports(pkg) {
+				stale[path] = true
+				grew = true
+			}
+		}
+		for key, results := range ctx.memo {
+			if staleMemo[key] {
+				continue
+			}
+			for _, res := range results {
+				if changed[filepath.Clean(res.dir)] || imports(res.pkg) {
+					staleMemo[key] = true
+					stale[res.path] = true
+					grew = true
+				}
+			}
+		}
+	}
+
+	for path := range stale {
+		ctx.Log.Debugf("Invalidating %s", path)
+		delete(ctx.allImports, path)
+		delete(ctx.importDirs, path)
+	}
+	for key := range staleMemo {
+		delete(ctx.memo, key)
+	}
+}
+
+// loadResult is the outcome of loading a single package. pkg is nil
+// if the package was skipped or couldn't be loaded.
+type loadResult struct {
+	path string
+	dir  string
+	pkg  *types.Package
+	src  *sourcePackage
+	errs []error
+}
+
+// loadPackage imports or type-checks the package path, as well as
+// its external test package if ctx.Tests is set. It may be called
+// concurrently.
+func (ctx *Context) loadPackage(path string) []loadResult {
+	ctx.Log.Debugf("Loading %s", path)
+	var buildPkg *build.Package
+	var err error
+	if dir, ok := ctx.workspace.dir(path); ok {
+		buildPkg, err = ctx.Build.ImportDir(dir, 0)
+	} else if importPath, ok := ctx.modCacheImportPath(path); ok {
+		// Packages in the module cache are read-only and
+		// outside of any GOPATH; load them by directory.
+		buildPkg, err = ctx.Build.ImportDir(path, 0)
+		path = importPath
+	} else {
+		buildPkg, err = ctx.Build.Import(path, ".", 0)
+	}
+	if _, ok := err.(*build.NoGoError); ok && (!ctx.Tests || len(buildPkg.TestGoFiles)+len(buildPkg.XTestGoFiles) == 0) {
+		// Directories without Go files are common in
+		// expanded patterns.
+		ctx.Log.Infof("Skipping %s: %s%s", path, err, ctx.platformHint(buildPkg))
+		return []loadResult{{path: path, dir: buildPkg.Dir}}
+	} else if !ok && err != nil {
+		return []loadResult{{path: path, errs: []error{&LoadError{path, ImportError, err}}}}
+	}
+	if buildPkg.Goroot && ctx.hostPlatform() {
+		// TODO what if the compiled package in GoRoot is
+		// outdated?
+		ctx.importMu.Lock()
+		pkg, err := gcimporter.Import(ctx.allImports, path)
+		ctx.importMu.Unlock()
+		if err != nil {
+			return []loadResult{{path: path, errs: []error{&LoadError{path, ImportError, err}}}}
+		}
+		return []loadResult{{path: path, dir: buildPkg.Dir, pkg: pkg}}
+	}
+
+	files, imports := buildPkg.GoFiles, buildPkg.Imports
+	if ctx.Tests {
+		files = append(append([]string(nil), files...), buildPkg.TestGoFiles...)
+		imports = append(append([]string(nil), imports...), buildPkg.TestImports...)
+	}
+	if len(files) == 0 && (!ctx.Tests || len(buildPkg.XTestGoFiles) == 0) {
+		// Not an error: packages whose files are all specific to
+		// cgo or to tests are common in expanded patterns.
+		ctx.Log.Infof("Skipping %s: %s", path, ctx.noFilesReason(buildPkg))
+		return []loadResult{{path: path, dir: buildPkg.Dir}}
+	}
+	var results []loadResult
+	if len(files) > 0 {
+		results = append(results, ctx.checkCached(path, buildPkg.Dir, files, imports))
+	}
+	if ctx.Tests && len(buildPkg.XTestGoFiles) > 0 {
+		results = append(results, ctx.checkCached(path+"_test", buildPkg.Dir, buildPkg.XTestGoFiles, buildPkg.XTestImports))
+	}
+	for i := range results {
+		results[i].dir = buildPkg.Dir
+	}
+	return results
+}
+
+// noFilesReason explains why the package pkg has no Go files that
+// can be type-checked.
+func (ctx *Context) noFilesReason(pkg *build.Package) string {
+	switch {
+	case len(pkg.CgoFiles) > 0:
+		return "only has cgo files"
+	case len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) > 0:
+		return "only has test files"
+	}
+	return "no Go files" + ctx.platformHint(pkg)
+}
+
+// knownPlatforms are the GOOS/GOARCH pairs platformHint tries.
+var knownPlatforms = []string{
+	"linux/amd64", "linux/386", "linux/arm", "linux/arm64",
+	"darwin/amd64", "darwin/arm64", "windows/amd64", "windows/386",
+	"freebsd/amd64", "openbsd/amd64", "netbsd/amd64", "dragonfly/amd64",
+	"solaris/amd64", "plan9/amd64", "android/arm", "js/wasm",
+}
+
+// platformHint suggests a GOOS and GOARCH for which pkg, whose Go
+// files are all excluded by build constraints, would have Go files.
+// It returns the empty string if there are no such files or no known
+// platform includes them.
+func (ctx *Context) platformHint(pkg *build.Package) string {
+	if pkg == nil || len(pkg.IgnoredGoFiles) == 0 {
+		return ""
+	}
+	for _, platform := range knownPlatforms {
+		index := strings.Index(platform, "/")
+		bctx := ctx.Build
+		bctx.GOOS, bctx.GOARCH = platform[:index], platform[index+1:]
+		if bctx.GOOS == ctx.Build.GOOS && bctx.GOARCH == ctx.Build.GOARCH {
+			continue
+		}
+		other, err := bctx.ImportDir(pkg.Dir, 0)
+		if err == nil && len(other.GoFiles) > 0 {
+			return fmt.Sprintf(" (but has Go files for GOOS=%s GOARCH=%s)", bctx.GOOS, bctx.GOARCH)
+		}
+	}
+	return ""
+}
+
+// PackageDirs returns the directories of the packages loaded so far,
+// including those that failed to load or had no Go files to load,
+// sorted and without duplicates.
+func (ctx *Context) PackageDirs() []string {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	var dirs []string
+	for _, dir := range ctx.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dedupStrings(dirs)
+}
+
+// checkFiles parses and type-checks files in dir as the package path.
+func (ctx *Context) checkFiles(path, dir string, files []string) loadResult {
+	var errors []error
+	fset := token.NewFileSet()
+	var astFiles []*ast.File
+	if len(files) == 0 {
+		errors = append(errors, &LoadError{path, ParseError, fmt.Errorf("No (non cgo) Go files")})
+		return loadResult{path: path, errs: errors}
+	}
+	for _, file := range files {
+		astFile, err := parseFile(fset, filepath.Join(dir, file))
+		if err != nil {
+			errors = append(errors, &LoadError{path, ParseError, err})
+			return loadResult{path: path, errs: errors}
+		}
+		astFiles = append(astFiles, astFile)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	pkg, err := check(ctx, path, dir, fset, astFiles, info)
+	if err != nil {
+		errors = append(errors, &LoadError{path, TypeError, err})
+		return loadResult{path: path, errs: errors}
+	}
+	return loadResult{path: path, pkg: pkg, src: newSourcePackage(pkg, fset, astFiles, info)}
+}
+
+// Func is a matched function. Its Pkg is the package the function was
+// found in, which for methods promoted from embedded fields differs
+// from the package declaring them, Object.Pkg(). Pkg also works
+// around issue 5815 (go/types: (*Func).Pkg() returns nil for methods
+// from GcImport'ed packages).
+//
+// Object is either a *types.Func or a package-level *types.Var
+// holding a function value.
+type Func struct {
+	types.Object
+	Pkg *types.Package
+	// recv is the type, T or *T, that a method promoted from an
+	// embedded field was found on. It is nil for all other
+	// functions.
+	recv types.Type
+}
+
+// Recv returns the receiver type of a method. For methods promoted
+// from embedded fields, that is the type, T or *T, they were found on
+// rather than the embedded type. It returns nil for functions.
+func (fnc Func) Recv() types.Type {
+	if fnc.recv != nil {
+		return fnc.recv
+	}
+	if sig, ok := fnc.Type().(*types.Signature); ok && sig.Recv() != nil && !fnc.isVar() {
+		return sig.Recv().Type()
+	}
+	return nil
+}
+
+// promotedFromStdlib reports whether fnc is a method declared in the
+// standard library but found on a type of another package, that is,
+// promoted through embedding.
+func (fnc Func) promotedFromStdlib() bool {
+	origin := fnc.Object.Pkg()
+	return origin != nil && origin.Path() != fnc.Pkg.Path() && isStdlib(origin.Path()) && !isStdlib(fnc.Pkg.Path())
+}
+
+// isStdlib reports whether path belongs to the standard library,
+// whose import paths don't start with a domain name.
+func isStdlib(path string) bool {
+	first := path
+	if index := strings.Index(path, "/"); index != -1 {
+		first = path[:index]
+	}
+	return !strings.Contains(first, ".")
+}
+
+// isInterfaceMethod reports whether fnc is a method declared by an
+// interface.
+func (fnc Func) isInterfaceMethod() bool {
+	if fnc.recv != nil {
+		_, ok := fnc.recv.Underlying().(*types.Interface)
+		return ok
+	}
+	sig, ok := fnc.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return false
+	}
+	_, ok = sig.Recv().Type().Underlying().(*types.Interface)
+	return ok
+}
+
+func (fnc Func) isVar() bool {
+	_, ok := fnc.Object.(*types.Var)
+	return ok
+}
+
+// getTypes returns the named types among objects.
+func getTypes(objects []types.Object) []*types.Named {
+	var named []*types.Named
+	for _, obj := range objects {
+		typ, ok := obj.(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if n, ok := typ.Type().(*types.Named); ok {
+			named = append(named, n)
+		}
+	}
+	return named
+}
+
+func getFunctions(objects []types.Object) []Func {
+	var funcs []Func
+
+	for _, obj := range objects {
+		if fnc, ok := obj.(*types.Func); ok {
+			funcs = append(funcs, Func{fnc, obj.Pkg(), nil})
+		} else if v, ok := obj.(*types.Var); ok {
+			if _, ok := v.Type().Underlying().(*types.Signature); ok {
+				funcs = append(funcs, Func{v, obj.Pkg(), nil})
+			}
+		} else {
+			typ, ok := obj.(*types.TypeName)
+			if !ok {
+				continue
+			}
+
+			named, ok := typ.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+
+			for i := 0; i < named.NumMethods(); i++ {
+				funcs = append(funcs, Func{named.Method(i), obj.Pkg(), nil})
+			}
+			if iface, ok := named.Underlying().(*types.Interface); ok {
+				for i := 0; i < iface.NumExplicitMethods(); i++ {
+					funcs = append(funcs, Func{iface.ExplicitMethod(i), obj.Pkg(), nil})
+				}
+			}
+		}
+	}
+
+	return funcs
+}
+
+// getPromotedMethods returns the methods that the types in named get
+// from their embedded fields, including the methods of embedded
+// interfaces. Ambiguous selectors are already excluded from method
+// sets; unexported methods of other packages are skipped, as they
+// can't be called.
+func getPromotedMethods(named []*types.Named) []Func {
+	var funcs []Func
+	for _, typ := range named {
+		pkg := typ.Obj().Pkg()
+		var recv types.Type = typ
+		if _, ok := typ.Underlying().(*types.Interface); !ok {
+			recv = types.NewPointer(typ)
+		}
+		values := types.NewMethodSet(typ)
+		mset := types.NewMethodSet(recv)
+		for i := 0; i < mset.Len(); i++ {
+			sel := mset.At(i)
+			fn := sel.Obj().(*types.Func)
+			if !fn.Exported() && fn.Pkg() != pkg {
+				continue
+			}
+			if isDeclaredBy(fn, typ) {
+				continue
+			}
+			var on types.Type = typ
+			if values.Lookup(fn.Pkg(), fn.Name()) == nil {
+				on = recv
+			}
+			funcs = append(funcs, Func{fn, pkg, on})
+		}
+	}
+	return funcs
+}
+
+// isDeclaredBy reports whether fn is declared by typ itself, rather
+// than promoted from one of its embedded fields.
+func isDeclaredBy(fn *types.Func, typ *types.Named) bool {
+	if iface, ok := typ.Underlying().(*types.Interface); ok {
+		for i := 0; i < iface.NumExplicitMethods(); i++ {
+			if iface.ExplicitMethod(i) == fn {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < typ.NumMethods(); i++ {
+		if typ.Method(i) == fn {
+			return true
+		}
+	}
+	return false
+}
+
+func noDot(s string) string {
+	index := strings.Index(s, "·")
+	if index == -1 {
+		return s
+	}
+
+	return s[:index]
+}
+
+var vendorPrefix = regexp.MustCompile(`(^|[^\w.~/-])(?:[\w.~-]+/)*vendor/`)
+
+// TypeString returns the string representation of typ that is used
+// for both matching and output, with vendor directory prefixes
+// stripped as with Context.CleanVendor.
+func TypeString(typ types.Type) string {
+	return typeString(typ, false)
+}
+
+// typeString is like TypeString, but keeps vendor directory prefixes
+// if keepVendor is set.
+func typeString(typ types.Type, keepVendor bool) string {
+	s := typ.String()
+	if !keepVendor {
+		s = vendorPrefix.ReplaceAllString(s, "$1")
+	}
+	return s
+}
+
+// TypeString returns the string representation of typ that is used
+// for both matching and output, honoring the CleanVendor setting of
+// the snapshot's Context.
+func (s *Snapshot) TypeString(typ types.Type) string {
+	return typeString(typ, s.keepVendor)
+}
+
+func argsToString(args *types.Tuple, keepVendor bool) string {
+	return FormatOptions{}.tupleString(newParams(args, keepVendor), false, nil)
+}
+
+func lastResultImplements(sig *types.Signature, iface *types.Interface) bool {
+	results := sig.Results()
+	if results.Len() == 0 {
+		return false
+	}
+
+	return types.Implements(results.At(results.Len()-1).Type(), iface)
+}
+
+// constructedType returns the type constructed by fnc, or nil if fnc
+// isn't a constructor. A constructor is a free function whose name
+// starts with New and whose first result is T or *T, with T being a
+// named type declared in the same package.
+func constructedType(fnc Func, sig *types.Signature) *types.Named {
+	if fnc.isVar() || sig.Recv() != nil || !strings.HasPrefix(fnc.Name(), "New") || sig.Results().Len() == 0 {
+		return nil
+	}
+
+	typ := sig.Results().At(0).Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != fnc.Pkg.Path() {
+		return nil
+	}
+
+	return named
+}
+
+// collectPackages records the paths of all packages whose types are
+// referenced by typ.
+func collectPackages(typ types.Type, pkgs map[string]bool) {
+	switch typ := typ.(type) {
+	case *types.Named:
+		if pkg := typ.Obj().Pkg(); pkg != nil {
+			pkgs[pkg.Path()] = true
+		}
+	case *types.Pointer:
+		collectPackages(typ.Elem(), pkgs)
+	case *types.Slice:
+		collectPackages(typ.Elem(), pkgs)
+	case *types.Array:
+		collectPackages(typ.Elem(), pkgs)
+	case *types.Chan:
+		collectPackages(typ.Elem(), pkgs)
+	case *types.Map:
+		collectPackages(typ.Key(), pkgs)
+		collectPackages(typ.Elem(), pkgs)
+	case *types.Signature:
+		collectTuplePackages(typ.Params(), pkgs)
+		collectTuplePackages(typ.Results(), pkgs)
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			collectPackages(typ.Field(i).Type(), pkgs)
+		}
+	case *types.Interface:
+		for i := 0; i < typ.NumMethods(); i++ {
+			collectPackages(typ.Method(i).Type(), pkgs)
+		}
+	}
+}
+
+func collectTuplePackages(tuple *types.Tuple, pkgs map[string]bool) {
+	for i := 0; i < tuple.Len(); i++ {
+		collectPackages(tuple.At(i).Type(), pkgs)
+	}
+}
+
+func returnsPointerImplementing(sig *types.Signature, iface *types.Interface) bool {
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		ptr, ok := results.At(i).Type().(*types.Pointer)
+		if ok && types.Implements(ptr, iface) {
+			return true
+		}
+	}
+
+	return false
+}
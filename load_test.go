@@ -0,0 +1,347 @@
+package uses
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// testdata is laid out as a GOPATH, including the module
+	// cache in its pkg/mod.
+	os.Setenv("GO111MODULE", "off")
+	os.Unsetenv("GOMODCACHE")
+	os.Unsetenv("GOWORK")
+	os.Exit(m.Run())
+}
+
+// newTestContext returns a Context that finds packages in testdata.
+func newTestContext(t testing.TB) *Context {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext()
+	ctx.Build.GOPATH = gopath
+	return ctx
+}
+
+// matchNames returns the names of the functions in matches.
+func matchNames(matches []Match) []string {
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.Func.Name())
+	}
+	return names
+}
+
+func TestLoadOtherPlatform(t *testing.T) {
+	tests := []struct {
+		goos  string
+		query Query
+		want  int
+	}{
+		{"linux", Query{Rets: []string{"int"}, Underlying: true}, 1},
+		{"linux", Query{Rets: []string{"string"}, Underlying: true}, 0},
+		{"windows", Query{Rets: []string{"int"}, Underlying: true}, 0},
+		{"windows", Query{Rets: []string{"string"}, Underlying: true}, 1},
+	}
+	for _, tt := range tests {
+		ctx := newTestContext(t)
+		ctx.Build.GOOS = tt.goos
+		s := Load(ctx, []string{"platform/a"})
+		if len(s.Errors) > 0 {
+			t.Fatalf("GOOS=%s: %v", tt.goos, s.Errors)
+		}
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != tt.want {
+			t.Errorf("GOOS=%s %v: got %v, want %d matches", tt.goos, tt.query.Rets, matchNames(matches), tt.want)
+		}
+	}
+}
+
+func TestLoadOtherPlatformStdlib(t *testing.T) {
+	ctx := newTestContext(t)
+	ctx.Build.GOOS = "windows"
+	ctx.Build.GOARCH = "amd64"
+	s := Load(ctx, []string{"platform/sys", "syscall"})
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	matches, err := s.Match(Query{Rets: []string{"syscall.Handle", "error"}, Name: "*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var open, createFile bool
+	for _, m := range matches {
+		switch m.Func.Pkg.Path() + "." + m.Func.Name() {
+		case "platform/sys.Open":
+			open = true
+		case "syscall.CreateFile":
+			createFile = true
+		}
+	}
+	if !open || !createFile {
+		t.Errorf("got %v, want platform/sys.Open and syscall.CreateFile", matchNames(matches))
+	}
+}
+
+func TestGOPATHOverride(t *testing.T) {
+	ctx := newTestContext(t)
+	ctx.Build.GOOS = "linux"
+	matches, errs := ctx.Search(Query{Packages: []string{"platform/..."}, Rets: []string{"platform/b.T"}})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if names := matchNames(matches); len(names) != 1 || names[0] != "F" {
+		t.Errorf("got %v, want [F]", names)
+	}
+
+	// The same import path in several roots resolves to the first
+	// of them, for searched and imported packages alike, and never
+	// to build.Default's GOPATH.
+	var roots []string
+	for i, typ := range []string{"int", "string", "bool"} {
+		root, err := ioutil.TempDir("", "uses")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(root)
+		roots = append(roots, root)
+		src := fmt.Sprintf("package p\n\ntype T %s\n\nfunc Root%d() {}\n", typ, i)
+		if err := os.MkdirAll(filepath.Join(root, "src", "dup", "p"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, "src", "dup", "p", "p.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// dup/use is only in the second root.
+	use := filepath.Join(roots[1], "src", "dup", "use")
+	if err := os.MkdirAll(use, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(use, "use.go"), []byte("package use\n\nimport \"dup/p\"\n\nfunc F() p.T { return p.T(0) }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(gopath string) { build.Default.GOPATH = gopath }(build.Default.GOPATH)
+	build.Default.GOPATH = roots[2]
+
+	sep := string(filepath.ListSeparator)
+	tests := []struct {
+		gopath string
+		root   string
+		typ    string
+	}{
+		{roots[0] + sep + roots[1], "Root0", "int"},
+		{roots[1] + sep + roots[0], "Root1", "string"},
+	}
+	for _, tt := range tests {
+		ctx := NewContext()
+		ctx.Build.GOPATH = tt.gopath
+		s := Load(ctx, []string{"dup/p", "dup/use"})
+		if len(s.Errors) > 0 {
+			t.Fatal(s.Errors)
+		}
+		matches, err := s.Match(Query{Name: "Root*"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := matchNames(matches); len(names) != 1 || names[0] != tt.root {
+			t.Errorf("GOPATH %s: got %v, want [%s]", tt.gopath, names, tt.root)
+		}
+		matches, err = s.Match(Query{Rets: []string{tt.typ}, Underlying: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := matchNames(matches); len(names) != 1 || names[0] != "F" {
+			t.Errorf("GOPATH %s: got %v, want dup/use to import dup/p from the same root", tt.gopath, names)
+		}
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "uses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	write := func(name, src string) {
+		name = filepath.Join(gopath, "src", name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("inv/a/a.go", "package a\n\nimport \"inv/b\"\n\nfunc F() b.T { return b.T(0) }\n")
+	write("inv/b/b.go", "package b\n\ntype T int\n")
+	write("inv/empty/README", "no Go files\n")
+
+	ctx := NewContext()
+	ctx.Build.GOPATH = gopath
+	count := func(typ string) int {
+		s := Load(ctx, []string{"inv/a", "inv/empty"})
+		if len(s.Errors) > 0 {
+			t.Fatal(s.Errors)
+		}
+		matches, err := s.Match(Query{Rets: []string{typ}, Underlying: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(matches)
+	}
+	if n := count("int"); n != 1 {
+		t.Fatalf("got %d matches returning int, want 1", n)
+	}
+	dirs := ctx.PackageDirs()
+	empty := filepath.Join(gopath, "src", "inv", "empty")
+	found := false
+	for _, dir := range dirs {
+		found = found || dir == empty
+	}
+	if !found {
+		t.Errorf("PackageDirs() = %v, want it to include %s", dirs, empty)
+	}
+
+	write("inv/b/b.go", "package b\n\ntype T string\n")
+	if n := count("string"); n != 0 {
+		t.Errorf("got %d matches returning string before Invalidate, want the memoized 0", n)
+	}
+	ctx.Invalidate([]string{filepath.Join(gopath, "src", "inv", "b")})
+	if n := count("string"); n != 1 {
+		t.Errorf("got %d matches returning string after Invalidate, want 1", n)
+	}
+}
+
+func TestCheckPanic(t *testing.T) {
+	ctx := newTestContext(t)
+	// The type checker reports errors through this callback, so
+	// panicking in it panics inside of the type checker.
+	ctx.context.Error = func(error) { panic("injected") }
+	s := Load(ctx, []string{"typeerr", "resolve"})
+	if len(s.Errors) != 1 || !strings.Contains(s.Errors[0].Error(), "type checker panicked: injected") {
+		t.Fatalf("got errors %v, want the recovered panic of typeerr", s.Errors)
+	}
+	matches, err := s.Match(Query{Rets: []string{"map[string]int"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := matchNames(matches); !reflect.DeepEqual(got, []string{"Map"}) {
+		t.Errorf("got %v, want the other package to load", got)
+	}
+}
+
+// recordLogger records messages by their level.
+type recordLogger struct{ warnings, info, debug []string }
+
+func (l *recordLogger) Warnf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+func (l *recordLogger) Infof(format string, args ...interface{}) {
+	l.info = append(l.info, fmt.Sprintf(format, args...))
+}
+func (l *recordLogger) Debugf(format string, args ...interface{}) {
+	l.debug = append(l.debug, fmt.Sprintf(format, args...))
+}
+
+func TestLoadGuards(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name        string
+		maxPackages int
+		run         context.Context
+		loaded      []string
+		skipped     []string
+		warning     string
+	}{
+		{"max packages", 2, context.Background(), []string{"blank/app", "blank/driver"}, nil, "Reached the limit of 2 packages, skipping the remaining 2"},
+		{"done", 0, canceled, nil, []string{"blank/app", "blank/driver", "blank/other", "blank/util"}, "Stopped loading packages (context canceled), skipping the remaining 4"},
+	}
+	for _, tt := range tests {
+		ctx := newTestContext(t)
+		log := &recordLogger{}
+		ctx.Log = log
+		ctx.MaxPackages = tt.maxPackages
+		ctx.Run = tt.run
+		s := Load(ctx, ctx.ResolvePackages([]string{"blank/..."}))
+		if !reflect.DeepEqual(s.Loaded, tt.loaded) || !reflect.DeepEqual(s.Skipped, tt.skipped) {
+			t.Errorf("%s: loaded %q and skipped %q, want %q and %q", tt.name, s.Loaded, s.Skipped, tt.loaded, tt.skipped)
+		}
+		if len(log.warnings) != 1 || log.warnings[0] != tt.warning {
+			t.Errorf("%s: got warnings %q, want %q", tt.name, log.warnings, tt.warning)
+		}
+	}
+}
+
+func TestLoadTests(t *testing.T) {
+	for _, tests := range []bool{false, true} {
+		ctx := newTestContext(t)
+		ctx.Tests = tests
+		s := Load(ctx, []string{"withtests", "withtests/onlytests"})
+		if len(s.Errors) > 0 {
+			t.Fatal(s.Errors)
+		}
+		matches, err := s.Match(Query{Rets: []string{"error"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range matches {
+			got = append(got, m.Func.Pkg.Path()+"."+m.Func.Name())
+		}
+		want := []string{"withtests.Open"}
+		if tests {
+			// Functions of the external test package are labeled
+			// with its _test import path.
+			want = []string{"withtests.Open", "withtests.mustOpen", "withtests_test.openTwice", "withtests/onlytests.fixture"}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Tests = %t: got %v, want %v", tests, got, want)
+		}
+	}
+}
+
+func TestNoFilesReason(t *testing.T) {
+	ctx := newTestContext(t)
+	ctx.Build.GOOS, ctx.Build.GOARCH = "linux", "amd64"
+	importDir := func(path string) *build.Package {
+		pkg, _ := ctx.Build.Import(path, ".", 0)
+		return pkg
+	}
+	tests := []struct {
+		name string
+		pkg  *build.Package
+		want string
+	}{
+		{"cgo", &build.Package{CgoFiles: []string{"a.go"}}, "only has cgo files"},
+		{"tests", &build.Package{TestGoFiles: []string{"a_test.go"}}, "only has test files"},
+		{"windows", importDir("platform/sys"), "no Go files (but has Go files for GOOS=windows GOARCH=amd64)"},
+		// No platform includes files that are always ignored.
+		{"ignored", importDir("platform/ignored"), "no Go files"},
+	}
+	for _, tt := range tests {
+		if got := ctx.noFilesReason(tt.pkg); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	log := &recordLogger{}
+	ctx.Log = log
+	Load(ctx, []string{"platform/sys"})
+	want := " (but has Go files for GOOS=windows GOARCH=amd64)"
+	if len(log.info) != 1 || !strings.HasPrefix(log.info[0], "Skipping platform/sys: ") || !strings.HasSuffix(log.info[0], want) {
+		t.Errorf("got %q", log.info)
+	}
+}
@@ -0,0 +1,1585 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Snapshot is the result of loading and type-checking a set of
+// packages. Loading is expensive, matching is cheap: a snapshot can
+// be matched against any number of queries, including concurrently.
+type Snapshot struct {
+	// mu protects the caches of resolved types, reachable objects
+	// and promoted methods.
+	mu  sync.Mutex
+	ctx *Context
+
+	objects   []types.Object
+	named     []*types.Named
+	funcs     []Func
+	Errors    []error
+	Fallbacks []string
+	// Loaded lists the packages that were loaded, and Skipped those
+	// that weren't because ctx.Run was done first.
+	Loaded  []string
+	Skipped []string
+
+	// pkgs maps the paths of the loaded packages to them, and
+	// sources to their sources if they were type-checked from source.
+	// Later loads of ctx don't affect them.
+	pkgs    map[string]*types.Package
+	sources map[string]*sourcePackage
+	// resolved caches the types resolved by lookupType, by name. It
+	// is protected by mu. Unlike the types' strings, the types don't
+	// depend on the query's comparison mode, such as Assignable or
+	// Underlying, so queries of all modes share it.
+	resolved map[string]types.Type
+	// reachable caches reachableFromExported. It is protected by mu.
+	reachable map[types.Object]bool
+	// promoted caches promotedMethods. It is protected by mu.
+	promoted []Func
+	// keepVendor is set if ctx.CleanVendor wasn't when loading.
+	keepVendor bool
+}
+
+// Load imports the packages in paths and returns a snapshot of the
+// functions they declare.
+func Load(ctx *Context, paths []string) *Snapshot {
+	objects, sources, loaded, skipped, errs := ctx.getObjects(paths)
+	pkgs := make(map[string]*types.Package)
+	for _, obj := range objects {
+		pkgs[obj.Pkg().Path()] = obj.Pkg()
+	}
+	return &Snapshot{
+		ctx:       ctx,
+		pkgs:      pkgs,
+		sources:   sources,
+		resolved:  make(map[string]types.Type),
+		objects:   objects,
+		named:     getTypes(objects),
+		funcs:     getFunctions(objects),
+		Errors:    errs,
+		Fallbacks: ctx.fallbacks(),
+		Loaded:    loaded,
+		Skipped:   skipped,
+
+		keepVendor: !ctx.CleanVendor,
+	}
+}
+
+// AnyType is the pseudo-type that matches any type in Args and Rets.
+const AnyType = "_"
+
+// FuncKind restricts the kind of functions to match.
+type FuncKind int
+
+const (
+	// KindAny matches both free functions and methods.
+	KindAny FuncKind = iota
+	// KindFunc only matches free functions, including package-level
+	// variables holding functions.
+	KindFunc
+	// KindMethod only matches methods.
+	KindMethod
+)
+
+// ErrorResult constrains the error results of functions.
+type ErrorResult int
+
+const (
+	// ErrorUnconstrained doesn't constrain a function's results.
+	ErrorUnconstrained ErrorResult = iota
+	// ErrorLast requires the last result to implement error.
+	ErrorLast
+	// ErrorAny requires any result to implement error.
+	ErrorAny
+	// ErrorNone requires no result to implement error.
+	ErrorNone
+)
+
+// Search loads the packages matching q.Packages, which may use the
+// patterns supported by ResolvePackages, and returns the functions in them
+// that satisfy q.
+func (ctx *Context) Search(q Query) ([]Match, []error) {
+	snapshot := Load(ctx, ctx.ResolvePackages(q.Packages))
+	matches, err := snapshot.Match(q)
+	if err != nil {
+		return nil, append(snapshot.Errors, err)
+	}
+	return matches, snapshot.Errors
+}
+
+// Query describes the functions to look for. All criteria that are
+// set have to be satisfied.
+type Query struct {
+	// Packages are the packages to search in when using
+	// Context.Search.
+	Packages []string
+	Args     []string
+	Rets     []string
+	// And requires all of Args and Rets to match, instead of any.
+	And bool
+	// SameArg requires a single parameter to match all of Args,
+	// rather than each of them being matched by any parameter. It is
+	// only useful with Assignable, to find parameters such as an
+	// io.ReadCloser given io.Reader and io.Closer.
+	SameArg bool
+	// MinMatches, if positive, requires at least this many of Args
+	// and Rets to match, in place of And.
+	MinMatches int
+	// Assignable matches parameters and results that are assignable
+	// to the types in Args and Rets, instead of identical to them.
+	// Searching for io.Reader thus finds functions taking *os.File.
+	Assignable bool
+	// Underlying matches parameters and results whose underlying
+	// type is identical to that of the types in Args and Rets, so
+	// that int finds functions taking a defined type such as
+	// time.Duration, and byte finds uint8.
+	Underlying bool
+	// Map types in Args and Rets match maps whose key and element
+	// types match theirs, so that map[string]_ finds maps of any
+	// element type with string keys.
+	//
+	// Channel types in Args and Rets match channels of the same
+	// direction whose element type matches theirs, so that
+	// <-chan T only finds receive-only channels. AnyChanDir makes
+	// bidirectional channel types match channels of any direction.
+	AnyChanDir bool
+	// Unqualified ignores the packages of named types when comparing
+	// types, so that Buffer matches bytes.Buffer or any other type
+	// named Buffer. Combined with Deref, it also matches *bytes.Buffer.
+	Unqualified bool
+	// resolved holds the types of Args and Rets for Assignable and
+	// Underlying.
+	resolved map[string]types.Type
+	// scoped maps the entries of Args and Rets that scopeQuery
+	// qualified to their qualified form.
+	scoped map[string]string
+	// NArgs and NRets, if not nil, constrain the number of
+	// parameters and results. They are combined with Args and Rets
+	// like those are combined with each other, that is, depending on
+	// And. As that has no equivalent in an Expr, they can't be
+	// combined with one.
+	NArgs *Range
+	NRets *Range
+	// Deref ignores a single level of pointer indirection on both
+	// sides when matching Args and Rets, so that bytes.Buffer and
+	// *bytes.Buffer match each other.
+	Deref bool
+	// Expr, if not nil, replaces the combination of Args and Rets
+	// with And by a boolean expression, as parsed by ParseExpr. It
+	// can't be combined with Positional, SameArg, MinMatches, NArgs
+	// or NRets.
+	Expr Expr
+	// Positional matches Args and Rets by position instead of as
+	// sets: the function has to have exactly as many parameters as
+	// there are Args, the first of which matching the first entry,
+	// and so on, and likewise for results. With And, both the
+	// parameters and the results have to match, otherwise either.
+	//
+	// In Args and Rets, AnyType (_) matches any type. Outside of Positional,
+	// it thus matches any function with at least one parameter or
+	// result respectively.
+	Positional bool
+	// Regex interprets Args and Rets as regular expressions, which
+	// have to match the entire type string, as in \*database/sql\.Rows?.
+	Regex bool
+	// ExcludeTypes lists types that never match Args and Rets, even
+	// _ or by assignability.
+	ExcludeTypes []string
+	// IgnoreCase compares the types of Args and Rets, including
+	// regular expressions, case-insensitively.
+	IgnoreCase bool
+	// patterns holds the compiled expressions of Args and Rets for
+	// Regex.
+	patterns map[string]*regexp.Regexp
+	// keepVendor keeps vendor directory prefixes in the type names
+	// that are matched, for snapshots loaded without
+	// Context.CleanVendor.
+	keepVendor bool
+
+	// ReturnsErrorType only matches functions whose last result
+	// implements this interface, such as a package's error type.
+	ReturnsErrorType string
+	// ReturnsPtrImplementing only matches functions returning a
+	// pointer that implements this interface.
+	ReturnsPtrImplementing string
+	// Constructors only matches free functions named New... whose
+	// first result is T or *T, T being a named type of the same
+	// package. Matches are grouped by T instead of by package.
+	Constructors bool
+	// ZeroArgConstructors is like Constructors, but only matches
+	// constructors without parameters.
+	ZeroArgConstructors bool
+	// MinDeps only matches functions whose signatures refer to the
+	// types of at least this many packages.
+	MinDeps int
+	// UsesPkg only matches functions whose signatures refer to a
+	// type of the package with this import path.
+	UsesPkg string
+	// CatchAll only matches functions like fmt.Println, whose final
+	// parameter is ...interface{}.
+	CatchAll bool
+	// ReturnsCleanup only matches functions returning a resource
+	// alongside a func() or func() error to release it.
+	ReturnsCleanup bool
+	// Directive only matches functions whose doc comment contains a
+	// directive (such as go:noinline) starting with this prefix.
+	// Only functions type-checked from source can match.
+	Directive string
+	// MinMethods only matches methods whose receiver type has at
+	// least this many methods.
+	MinMethods int
+	// AssignableTo only matches functions with a return value
+	// assignable to any of these types, or all of them if
+	// AssignableToAll is set.
+	AssignableTo    []string
+	AssignableToAll bool
+	// SelfConsistentReturns only matches functions returning both a
+	// concrete type and an interface implemented by it, such as
+	// func() (*Impl, Iface, error).
+	SelfConsistentReturns bool
+	// VariadicOf only matches variadic functions whose variadic
+	// parameter has this element type, as in func(...string). It is
+	// matched like the types of Args.
+	VariadicOf string
+	// ConcreteArgs and InterfaceArgs, if not nil, require exactly
+	// that many parameters of concrete and interface types
+	// respectively.
+	ConcreteArgs  *int
+	InterfaceArgs *int
+	// Producers only matches functions returning a channel that can
+	// be received from, optionally restricted to channels of
+	// ProducerElem, which is matched like the types of Args.
+	Producers    bool
+	ProducerElem string
+	// StructByType compares struct types in Args and Rets by their
+	// field types only, so that struct{ X int } matches
+	// struct{ Y int }.
+	StructByType bool
+	// ContextNotFirst only matches functions that take a
+	// context.Context, but not as their first parameter.
+	ContextNotFirst bool
+	// ResultErrorPairing only matches functions returning (T, error)
+	// or (*T, error) whose package also declares an error type named
+	// after T, such as TError.
+	ResultErrorPairing bool
+	// ForwardsResults only matches functions whose body passes all
+	// results of a call as the arguments of another, as in f(g()).
+	// Only functions type-checked from source can match.
+	ForwardsResults bool
+	// AssignableSig only matches functions whose signature is
+	// assignable to this named function type, such as
+	// net/http.HandlerFunc. Methods are considered as method values,
+	// with their receiver bound.
+	AssignableSig string
+	// OutParams only matches functions returning nothing or just an
+	// error that take a pointer parameter, presumably to write their
+	// result to. If set, Args constrain the pointer's element type
+	// instead of the parameters.
+	OutParams bool
+	// HasBody and ExternalOnly only match functions declared with
+	// and without a body respectively, the latter usually being
+	// implemented in assembly. Only functions type-checked from source
+	// can match.
+	HasBody      bool
+	ExternalOnly bool
+	// DuplicateArgs only matches functions taking several parameters
+	// of the same type. If Args are set, the duplicated type has to
+	// be one of them.
+	DuplicateArgs bool
+	// ReturnsPointer, ReturnsErrorOnly, ReturnsBool and
+	// ReturnsString only match functions with a single result that
+	// is a pointer, error, bool or string respectively.
+	ReturnsPointer   bool
+	ReturnsErrorOnly bool
+	ReturnsBool      bool
+	ReturnsString    bool
+	// NoPromotedStdlib matches the methods types get from their
+	// embedded fields, like Promoted, except those promoted from
+	// types of the standard library, such as sync.Mutex's Lock.
+	NoPromotedStdlib bool
+	// Promoted also matches the methods that named types get from
+	// their embedded fields.
+	Promoted bool
+	// Variadic only matches variadic functions.
+	Variadic bool
+	// ReturnsError constrains which results implement error.
+	ReturnsError ErrorResult
+	// Kind restricts matches to free functions or methods.
+	Kind FuncKind
+	// Name only matches functions whose name matches this glob, as
+	// in New*. Methods match on their bare name.
+	Name string
+	// ArgName and RetName only match functions with a parameter or
+	// result, respectively, whose name matches this glob, as in ctx.
+	// Unnamed parameters and results never match.
+	ArgName string
+	RetName string
+	// ReachableFromExported only matches functions that are
+	// transitively referenced by the exported API of their package,
+	// excluding dead and internal-only code. Only functions
+	// type-checked from source can match.
+	ReachableFromExported bool
+}
+
+// HasCriteria reports whether q constrains the functions to match.
+func (q Query) HasCriteria() bool {
+	return len(q.Args)+len(q.Rets) > 0 || q.ReturnsErrorType != "" || q.ReturnsPtrImplementing != "" ||
+		q.Constructors || q.ZeroArgConstructors || q.MinDeps > 0 || q.UsesPkg != "" || q.CatchAll ||
+		q.ReturnsCleanup || q.Directive != "" || q.MinMethods > 0 ||
+		len(q.AssignableTo) > 0 || q.SelfConsistentReturns ||
+		q.VariadicOf != "" || q.ConcreteArgs != nil || q.InterfaceArgs != nil ||
+		q.Producers || q.ContextNotFirst || q.ResultErrorPairing ||
+		q.ForwardsResults || q.AssignableSig != "" ||
+		q.OutParams || q.HasBody || q.ExternalOnly || q.DuplicateArgs ||
+		q.ReturnsPointer || q.ReturnsErrorOnly || q.ReturnsBool || q.ReturnsString ||
+		q.ReachableFromExported || q.Name != "" || q.Kind != KindAny ||
+		q.NArgs != nil || q.NRets != nil || q.ReturnsError != ErrorUnconstrained ||
+		q.Variadic || q.Expr != nil || q.ArgName != "" || q.RetName != ""
+}
+
+// Match is a function that satisfied a query.
+type Match struct {
+	// Key is the group the match belongs to: its package path, or
+	// the constructed type when looking for constructors.
+	Key  string
+	Func Func
+	Sig  *types.Signature
+	// Via is args, rets or both, depending on which of the query's
+	// types the function matched. It is empty if the query has no
+	// types.
+	Via string
+	// Detail optionally explains why the function matched.
+	Detail string
+	// Confidence, between 0 and 1, describes how well the function
+	// fits the query's heuristic filters, such as Constructors. It is
+	// 1 for queries without heuristics.
+	Confidence float64
+	// Pos is the position of the function's declaration. It is only
+	// valid for packages type-checked from source.
+	Pos token.Position
+	// Doc is the first line of the function's doc comment. It is only
+	// available for packages type-checked from source.
+	Doc string
+	// Test reports whether the function is declared in a _test.go
+	// file.
+	Test bool
+	// query is the query as matched against the function's package.
+	query Query
+}
+
+// Matcher is like Query.Matcher for the query that found m, but
+// matches types the way that query did for m's package: with local
+// type names scoped to the package, and types resolved for
+// Assignable and Underlying.
+func (m Match) Matcher(targets []string) func(types.Type) bool {
+	return m.query.Matcher(targets)
+}
+
+// InterfaceMethod reports whether m is a method declared by an
+// interface.
+func (m Match) InterfaceMethod() bool {
+	return m.Func.isInterfaceMethod()
+}
+
+// PromotedFrom returns the receiver type of the declaration of a
+// method promoted from an embedded field, or the empty string if m
+// isn't such a method.
+func (m Match) PromotedFrom() string {
+	if m.Func.recv == nil {
+		return ""
+	}
+	return m.query.typeString(m.Sig.Recv().Type())
+}
+
+// matchTypes checks sig against q.Expr, or q.Args and q.Rets as well
+// as q.NArgs and q.NRets.
+func (q Query) matchTypes(sig *types.Signature) bool {
+	if q.Expr != nil {
+		return q.Expr.eval(q, sig)
+	}
+	if q.SameArg && len(q.Args) > 0 {
+		anyArg := q.sameEntry(sig.Params(), q.Args, sig.Variadic())
+		anyRet, allRet, _ := q.checkTypes(sig.Results(), q.Rets, false)
+		return ((!q.And && (anyArg || anyRet)) || (q.And && anyArg && allRet)) &&
+			(q.NArgs == nil || q.NArgs.Contains(sig.Params().Len())) &&
+			(q.NRets == nil || q.NRets.Contains(sig.Results().Len()))
+	}
+	if q.MinMatches > 0 {
+		return q.matchedCount(sig) >= q.MinMatches &&
+			(q.NArgs == nil || q.NArgs.Contains(sig.Params().Len())) &&
+			(q.NRets == nil || q.NRets.Contains(sig.Results().Len()))
+	}
+	if q.NArgs != nil || q.NRets != nil {
+		return q.matchShape(sig)
+	}
+	// Searching for a single type is by far the most common query.
+	// With only one type there is no difference between any and
+	// all, which lets us skip checkTypes and its allocations.
+	switch {
+	case len(q.Args)+len(q.Rets) == 0:
+		return true
+	case q.Positional:
+	case len(q.Args) == 1 && len(q.Rets) == 0:
+		return q.tupleHasType(sig.Params(), q.Args[0], sig.Variadic())
+	case len(q.Rets) == 1 && len(q.Args) == 0:
+		return q.tupleHasType(sig.Results(), q.Rets[0], false)
+	}
+	return q.matchAnyAll(sig)
+}
+
+// matchAnyAll checks sig against q.Args and q.Rets, requiring any or,
+// if q.And is set, all of them to match. It is the general case of
+// matchTypes.
+func (q Query) matchAnyAll(sig *types.Signature) bool {
+	anyArg, allArg, _ := q.checkTypes(sig.Params(), q.Args, sig.Variadic())
+	anyRet, allRet, _ := q.checkTypes(sig.Results(), q.Rets, false)
+	return (!q.And && (anyArg || anyRet)) || (q.And && allArg && allRet)
+}
+
+// Terms returns the types that q matches parameters and results
+// against: Args and Rets, followed by the types of the arg: and ret:
+// terms of Expr.
+func (q Query) Terms() (args, rets []string) {
+	args = append([]string(nil), q.Args...)
+	rets = append([]string(nil), q.Rets...)
+	if q.Expr != nil {
+		args, rets = q.Expr.terms(args, rets)
+	}
+	return args, rets
+}
+
+// targets returns all types the query matches parameters and results
+// against, including the element types of VariadicOf and
+// ProducerElem.
+func (q Query) targets() []string {
+	targets := append(append([]string(nil), q.Args...), q.Rets...)
+	if q.Expr != nil {
+		targets = q.Expr.types(targets)
+	}
+	for _, target := range []string{q.VariadicOf, q.ProducerElem} {
+		if target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// CompilePatterns compiles the regular expressions of Args and Rets
+// if Regex is set, reporting the first invalid one. Match calls it
+// as needed; calling it beforehand validates a query without loading
+// any packages.
+func (q *Query) CompilePatterns() error {
+	if !q.Regex || q.patterns != nil {
+		return nil
+	}
+	patterns := make(map[string]*regexp.Regexp)
+	for _, expr := range q.targets() {
+		flags := ""
+		if q.IgnoreCase {
+			flags = "(?i)"
+		}
+		re, err := regexp.Compile(flags + `^(?:` + expr + `)$`)
+		if err != nil {
+			return fmt.Errorf("Invalid type pattern %s: %s", expr, err)
+		}
+		patterns[expr] = re
+	}
+	q.patterns = patterns
+	return nil
+}
+
+// matchShape is the general form of matchTypes, combining the type
+// criteria with the counts of parameters and results.
+func (q Query) matchShape(sig *types.Signature) bool {
+	var conds []bool
+	if len(q.Args) > 0 {
+		any, all, _ := q.checkTypes(sig.Params(), q.Args, sig.Variadic())
+		conds = append(conds, (q.And && all) || (!q.And && any))
+	}
+	if len(q.Rets) > 0 {
+		any, all, _ := q.checkTypes(sig.Results(), q.Rets, false)
+		conds = append(conds, (q.And && all) || (!q.And && any))
+	}
+	if q.NArgs != nil {
+		conds = append(conds, q.NArgs.Contains(sig.Params().Len()))
+	}
+	if q.NRets != nil {
+		conds = append(conds, q.NRets.Contains(sig.Results().Len()))
+	}
+
+	for _, ok := range conds {
+		if ok && !q.And {
+			return true
+		}
+		if !ok && q.And {
+			return false
+		}
+	}
+	return q.And
+}
+
+// Range is an inclusive range of counts. A negative Max is
+// unbounded.
+type Range struct {
+	Min, Max int
+}
+
+// ParseRange parses a count, such as 3, or a range of counts, such as
+// 2..4 or 2.., into a Range.
+func ParseRange(s string) (*Range, error) {
+	lo, hi := s, s
+	if index := strings.Index(s, ".."); index != -1 {
+		lo, hi = s[:index], s[index+2:]
+	}
+	min, err := strconv.Atoi(lo)
+	if err != nil || min < 0 {
+		return nil, fmt.Errorf("Invalid count %s", s)
+	}
+	max := -1
+	if hi != "" {
+		max, err = strconv.Atoi(hi)
+		if err != nil || max < min {
+			return nil, fmt.Errorf("Invalid count %s", s)
+		}
+	}
+	return &Range{min, max}, nil
+}
+
+// Contains reports whether n is within r.
+func (r Range) Contains(n int) bool {
+	return n >= r.Min && (r.Max < 0 || n <= r.Max)
+}
+
+// via describes which of q's types sig matched.
+func (q Query) via(sig *types.Signature) string {
+	args, _, _ := q.checkTypes(sig.Params(), q.Args, sig.Variadic())
+	rets, _, _ := q.checkTypes(sig.Results(), q.Rets, false)
+	switch {
+	case args && rets:
+		return "both"
+	case args:
+		return "args"
+	case rets:
+		return "rets"
+	}
+	return ""
+}
+
+// typeString is TypeString for the snapshot the query is matched
+// against.
+func (q Query) typeString(typ types.Type) string {
+	return typeString(typ, q.keepVendor)
+}
+
+// typeMatches reports whether typ matches the query type target.
+func (q Query) typeMatches(typ types.Type, target string) bool {
+	if len(q.ExcludeTypes) > 0 && containsString(q.ExcludeTypes, q.typeString(typ)) {
+		return false
+	}
+	if target == AnyType {
+		return true
+	}
+	if q.Deref {
+		typ = deref(typ)
+	}
+	if target, ok := q.resolved[target]; ok {
+		if q.Deref {
+			target = deref(target)
+		}
+		if q.Underlying && types.Identical(typ.Underlying(), target.Underlying()) {
+			return true
+		}
+		return q.Assignable && types.AssignableTo(typ, target)
+	}
+	s := q.typeString(typ)
+	if re, ok := q.patterns[target]; ok {
+		return re.MatchString(s)
+	}
+	if m, ok := typ.(*types.Map); ok {
+		if key, elem, ok := parseMap(target); ok {
+			return q.typeMatches(m.Key(), key) && q.typeMatches(m.Elem(), elem)
+		}
+	}
+	if ch, ok := typ.(*types.Chan); ok {
+		if dir, elem, ok := parseChan(target); ok {
+			if dir != ch.Dir() && !(q.AnyChanDir && dir == types.SendRecv) {
+				return false
+			}
+			return q.typeMatches(ch.Elem(), elem)
+		}
+	}
+	if q.Deref {
+		target = strings.TrimPrefix(target, "*")
+	}
+	if q.StructByType {
+		s, target = stripFieldNames(s), stripFieldNames(target)
+	}
+	if q.Unqualified {
+		unqualified := FormatOptions{Qualification: QualifyNone}
+		s, target = unqualified.qualify(s), unqualified.qualify(target)
+	}
+	if q.IgnoreCase {
+		return strings.EqualFold(s, target)
+	}
+	return s == target
+}
+
+// parseMap splits a map type such as map[string]_ into its key and
+// element types.
+func parseMap(target string) (key, elem string, ok bool) {
+	if !strings.HasPrefix(target, "map[") {
+		return "", "", false
+	}
+	depth := 0
+	for i := len("map"); i < len(target); i++ {
+		switch target[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return target[len("map["):i], target[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// parseChan splits a channel type such as <-chan int into its
+// direction and element type.
+func parseChan(target string) (types.ChanDir, string, bool) {
+	var dir types.ChanDir
+	var elem string
+	switch {
+	case strings.HasPrefix(target, "<-chan "):
+		dir, elem = types.RecvOnly, target[len("<-chan "):]
+	case strings.HasPrefix(target, "chan<- "):
+		dir, elem = types.SendOnly, target[len("chan<- "):]
+	case strings.HasPrefix(target, "chan "):
+		dir, elem = types.SendRecv, target[len("chan "):]
+	default:
+		return 0, "", false
+	}
+	elem = strings.TrimSpace(elem)
+	if strings.HasPrefix(elem, "(") && strings.HasSuffix(elem, ")") {
+		// Element types that are channels themselves may be
+		// parenthesized, as in chan (<-chan int).
+		elem = elem[1 : len(elem)-1]
+	}
+	return dir, elem, true
+}
+
+// deref strips a single level of pointer indirection from typ.
+func deref(typ types.Type) types.Type {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return typ
+}
+
+// Matcher returns a function reporting whether a type matches any of
+// targets.
+func (q Query) Matcher(targets []string) func(types.Type) bool {
+	return func(typ types.Type) bool {
+		for _, target := range targets {
+			if scoped, ok := q.scoped[target]; ok {
+				target = scoped
+			}
+			if q.typeMatches(typ, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// entryMatches reports whether the i'th entry of tuple matches
+// target. The final entry of a variadic parameter list also matches
+// its element type, so that int finds func(args ...int).
+func (q Query) entryMatches(tuple *types.Tuple, i int, target string, variadic bool) bool {
+	typ := tuple.At(i).Type()
+	if q.typeMatches(typ, target) {
+		return true
+	}
+	if variadic && i == tuple.Len()-1 {
+		return q.typeMatches(typ.(*types.Slice).Elem(), target)
+	}
+	return false
+}
+
+// checkTypes reports whether any and all of types appear in args,
+// and how many of them do.
+func (q Query) checkTypes(args *types.Tuple, types []string, variadic bool) (any, all bool, n int) {
+	if q.Positional && len(types) > 0 {
+		if q.positionalMatch(args, types, variadic) {
+			return true, true, len(types)
+		}
+		return false, false, 0
+	}
+	matched := make([]bool, len(types))
+	for i := 0; i < args.Len(); i++ {
+		for k, toCheck := range types {
+			if q.entryMatches(args, i, toCheck, variadic) {
+				matched[k] = true
+				any = true
+			}
+		}
+	}
+
+	for _, b := range matched {
+		if b {
+			n++
+		}
+	}
+
+	return any, n == len(types), n
+}
+
+// matchedCount returns how many of the types in Args and Rets sig
+// matches.
+func (q Query) matchedCount(sig *types.Signature) int {
+	_, _, args := q.checkTypes(sig.Params(), q.Args, sig.Variadic())
+	_, _, rets := q.checkTypes(sig.Results(), q.Rets, false)
+	return args + rets
+}
+
+// sameEntry reports whether a single entry of tuple matches all of
+// targets.
+func (q Query) sameEntry(tuple *types.Tuple, targets []string, variadic bool) bool {
+	for i := 0; i < tuple.Len(); i++ {
+		all := true
+		for _, target := range targets {
+			if !q.entryMatches(tuple, i, target, variadic) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// positionalMatch reports whether tuple has exactly as many entries
+// as targets, each matching the target at the same position.
+func (q Query) positionalMatch(tuple *types.Tuple, targets []string, variadic bool) bool {
+	if tuple.Len() != len(targets) {
+		return false
+	}
+	for i, target := range targets {
+		if !q.entryMatches(tuple, i, target, variadic) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q Query) tupleHasType(tuple *types.Tuple, typ string, variadic bool) bool {
+	for i := 0; i < tuple.Len(); i++ {
+		if q.entryMatches(tuple, i, typ, variadic) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsSource reports whether q uses criteria that only packages
+// type-checked from source can satisfy, including unqualified names
+// of types declared in the searched packages.
+func (q Query) NeedsSource() bool {
+	if q.Directive != "" || q.HasBody || q.ExternalOnly || q.ForwardsResults || q.ReachableFromExported {
+		return true
+	}
+	if q.Unqualified {
+		// Unqualified names aren't resolved in packages' scopes.
+		return false
+	}
+	for _, target := range q.targets() {
+		for _, m := range unqualifiedIdent.FindAllStringSubmatchIndex(target, -1) {
+			if end := m[5]; end < len(target) && (target[end] == '.' || target[end] == '/') {
+				// Part of a package path
+				continue
+			}
+			switch ident := target[m[4]:m[5]]; ident {
+			case "_", "func", "map", "chan", "struct", "interface":
+			default:
+				if types.Universe.Lookup(ident) == nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+var unqualifiedIdent = regexp.MustCompile(`(^|[^\w./])([\pL_][\pL\pN_]*)`)
+
+// scopeQuery qualifies the unqualified type names in q's targets that
+// refer to types declared in pkg, so that queries can refer to a
+// package's unexported types by name. This only applies to packages
+// type-checked from source, and not to Regex and Unqualified queries.
+func (s *Snapshot) scopeQuery(q Query, pkg *types.Package) Query {
+	if _, ok := s.sources[pkg.Path()]; !ok || q.Regex || q.Unqualified {
+		return q
+	}
+	scoped := make(map[string]string)
+	qualifyType := func(typ string) string {
+		out := unqualifiedIdent.ReplaceAllStringFunc(typ, func(m string) string {
+			sub := unqualifiedIdent.FindStringSubmatch(m)
+			tn, ok := pkg.Scope().Lookup(sub[2]).(*types.TypeName)
+			if !ok {
+				return m
+			}
+			return sub[1] + q.typeString(tn.Type())
+		})
+		if out != typ {
+			scoped[typ] = out
+		}
+		return out
+	}
+	qualify := func(entries []string) []string {
+		out := make([]string, len(entries))
+		for i, entry := range entries {
+			out[i] = qualifyType(entry)
+		}
+		return out
+	}
+	q.Args = qualify(q.Args)
+	q.Rets = qualify(q.Rets)
+	if q.VariadicOf != "" {
+		q.VariadicOf = qualifyType(q.VariadicOf)
+	}
+	if q.ProducerElem != "" {
+		q.ProducerElem = qualifyType(q.ProducerElem)
+	}
+	if q.Expr != nil {
+		q.Expr = q.Expr.mapTypes(qualifyType)
+	}
+	q.scoped = scoped
+	return q
+}
+
+var wildcard = regexp.MustCompile(`\b_\b`)
+
+// resolveTargets resolves the query types of q that have to be
+// compared as types rather than as strings.
+func (s *Snapshot) resolveTargets(q Query) (Query, error) {
+	if !q.Assignable && !q.Underlying {
+		return q, nil
+	}
+	var targets []string
+	for _, target := range q.targets() {
+		// Wildcards, whether on their own or in a type such as
+		// map[string]_, are matched structurally.
+		if !wildcard.MatchString(target) {
+			targets = append(targets, target)
+		}
+	}
+	typs, err := s.lookupTypes(targets)
+	if err != nil {
+		return q, err
+	}
+	q.resolved = make(map[string]types.Type)
+	for i, target := range targets {
+		q.resolved[target] = typs[i]
+	}
+	return q, nil
+}
+
+// scopedQueries scopes and resolves a query for each package, as
+// needed. Types that only some packages declare don't resolve in the
+// others, whose functions then can't match; that is only an error if
+// the types resolve in no package at all.
+type scopedQueries struct {
+	s        *Snapshot
+	q        Query
+	queries  map[string]Query
+	failed   map[string]bool
+	firstErr error
+	resolved bool
+}
+
+func (s *Snapshot) newScopedQueries(q Query) *scopedQueries {
+	q.keepVendor = s.keepVendor
+	return &scopedQueries{s: s, q: q, queries: make(map[string]Query), failed: make(map[string]bool)}
+}
+
+// get returns the query for pkg, reporting whether its types
+// resolved.
+func (sq *scopedQueries) get(pkg *types.Package) (Query, bool) {
+	path := pkg.Path()
+	if q, ok := sq.queries[path]; ok {
+		return q, true
+	}
+	if sq.failed[path] {
+		return sq.q, false
+	}
+	q, err := sq.s.resolveTargets(sq.s.scopeQuery(sq.q, pkg))
+	if err != nil {
+		sq.failed[path] = true
+		if sq.firstErr == nil {
+			sq.firstErr = err
+		}
+		return sq.q, false
+	}
+	sq.queries[path] = q
+	sq.resolved = true
+	return q, true
+}
+
+// err returns the error of resolving the query's types if they
+// didn't resolve in any package.
+func (sq *scopedQueries) err() error {
+	if sq.resolved {
+		return nil
+	}
+	return sq.firstErr
+}
+
+// promotedMethods returns the methods promoted from embedded fields
+// of the snapshot's named types.
+func (s *Snapshot) promotedMethods() []Func {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.promoted == nil {
+		s.promoted = getPromotedMethods(s.named)
+	}
+	return s.promoted
+}
+
+// Match returns all functions in the snapshot that satisfy q, in the
+// order they were loaded.
+func (s *Snapshot) Match(q Query) ([]Match, error) {
+	if q.Expr != nil && (q.Positional || q.SameArg || q.MinMatches > 0 || q.NArgs != nil || q.NRets != nil) {
+		return nil, errors.New("Expr can't be combined with Positional, SameArg, MinMatches, NArgs or NRets")
+	}
+	if err := q.CompilePatterns(); err != nil {
+		return nil, err
+	}
+	errorIface, err := s.lookupInterface(q.ReturnsErrorType)
+	if err != nil {
+		return nil, err
+	}
+	ptrIface, err := s.lookupInterface(q.ReturnsPtrImplementing)
+	if err != nil {
+		return nil, err
+	}
+	assignableTo, err := s.lookupTypes(q.AssignableTo)
+	if err != nil {
+		return nil, err
+	}
+	var sigType types.Type
+	if q.AssignableSig != "" {
+		typs, err := s.lookupTypes([]string{q.AssignableSig})
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := typs[0].Underlying().(*types.Signature); !ok {
+			return nil, fmt.Errorf("%s is not a function type", q.AssignableSig)
+		}
+		sigType = typs[0]
+	}
+
+	var contextType types.Type
+	if q.ContextNotFirst {
+		typs, err := s.lookupTypes([]string{"context.Context"})
+		if err != nil {
+			return nil, err
+		}
+		contextType = typs[0]
+	}
+	constructors := q.Constructors || q.ZeroArgConstructors
+
+	var reachable map[types.Object]bool
+	if q.ReachableFromExported {
+		reachable = s.reachableFromExported()
+	}
+
+	scoped := s.newScopedQueries(q)
+	var matches []Match
+	funcs := s.funcs
+	if q.Promoted || q.NoPromotedStdlib {
+		funcs = append(funcs[:len(funcs):len(funcs)], s.promotedMethods()...)
+	}
+	for _, fnc := range funcs {
+		sig, ok := fnc.Type().Underlying().(*types.Signature)
+		if !ok {
+			// Skipping over builtins
+			continue
+		}
+
+		if method := isMethod(fnc.Object); (q.Kind == KindFunc && method) || (q.Kind == KindMethod && !method) {
+			continue
+		}
+
+		if q.Name != "" {
+			if ok, err := path.Match(q.Name, fnc.Name()); err != nil {
+				return nil, fmt.Errorf("Invalid name pattern %s: %s", q.Name, err)
+			} else if !ok {
+				continue
+			}
+		}
+
+		if q.ArgName != "" {
+			if ok, err := tupleHasName(sig.Params(), q.ArgName); err != nil {
+				return nil, fmt.Errorf("Invalid parameter name pattern %s: %s", q.ArgName, err)
+			} else if !ok {
+				continue
+			}
+		}
+		if q.RetName != "" {
+			if ok, err := tupleHasName(sig.Results(), q.RetName); err != nil {
+				return nil, fmt.Errorf("Invalid result name pattern %s: %s", q.RetName, err)
+			} else if !ok {
+				continue
+			}
+		}
+
+		pq, ok := scoped.get(fnc.Pkg)
+		if !ok {
+			continue
+		}
+
+		if errorIface != nil && !lastResultImplements(sig, errorIface) {
+			continue
+		}
+
+		if ptrIface != nil && !returnsPointerImplementing(sig, ptrIface) {
+			continue
+		}
+
+		if q.ReturnsError != ErrorUnconstrained && !matchErrorResult(sig, q.ReturnsError) {
+			continue
+		}
+
+		if q.Variadic && !sig.Variadic() {
+			continue
+		}
+
+		if q.CatchAll && !isCatchAll(sig) {
+			continue
+		}
+
+		if q.VariadicOf != "" {
+			elem := variadicElem(sig)
+			if elem == nil || !pq.typeMatches(elem, pq.VariadicOf) {
+				continue
+			}
+		}
+
+		if q.ConcreteArgs != nil || q.InterfaceArgs != nil {
+			concrete, iface := countParamKinds(sig)
+			if (q.ConcreteArgs != nil && concrete != *q.ConcreteArgs) || (q.InterfaceArgs != nil && iface != *q.InterfaceArgs) {
+				continue
+			}
+		}
+
+		confidence := 1.0
+		if q.OutParams {
+			if !pq.hasOutParam(sig) {
+				continue
+			}
+			pq.Args = nil
+			confidence *= outParamConfidence(sig)
+		}
+
+		if sigType != nil && !types.AssignableTo(sig, sigType) {
+			continue
+		}
+
+		if q.Producers && !pq.returnsRecvChan(sig) {
+			continue
+		}
+
+		if q.ReturnsCleanup && !hasCleanupResult(sig) {
+			continue
+		}
+
+		if q.Directive != "" && !hasDirective(s.funcDecl(fnc), q.Directive) {
+			continue
+		}
+
+		if q.HasBody || q.ExternalOnly {
+			decl := s.funcDecl(fnc)
+			if decl == nil || (q.HasBody && decl.Body == nil) || (q.ExternalOnly && decl.Body != nil) {
+				continue
+			}
+		}
+
+		if (q.ReturnsPointer || q.ReturnsErrorOnly || q.ReturnsBool || q.ReturnsString) && !q.matchSingleResult(sig) {
+			continue
+		}
+
+		if q.NoPromotedStdlib && fnc.promotedFromStdlib() {
+			continue
+		}
+
+		if reachable != nil && !reachable[fnc.Object] {
+			continue
+		}
+
+		if q.MinMethods > 0 && (sig.Recv() == nil || methodCount(sig.Recv().Type()) < q.MinMethods) {
+			continue
+		}
+
+		if len(assignableTo) > 0 && !returnsAssignable(sig, assignableTo, q.AssignableToAll) {
+			continue
+		}
+
+		if q.MinDeps > 0 || q.UsesPkg != "" {
+			deps := make(map[string]bool)
+			collectPackages(sig, deps)
+			if len(deps) < q.MinDeps {
+				continue
+			}
+			if q.UsesPkg != "" && !deps[q.UsesPkg] {
+				continue
+			}
+		}
+
+		var details []string
+		if q.SelfConsistentReturns {
+			concrete, iface := selfConsistentPair(sig)
+			if concrete == nil {
+				continue
+			}
+			details = append(details, fmt.Sprintf("%s implements %s", pq.typeString(concrete), pq.typeString(iface)))
+		}
+
+		if q.ResultErrorPairing {
+			errType := pairedErrorType(sig)
+			if errType == nil {
+				continue
+			}
+			details = append(details, "errors: "+pq.typeString(errType.Type()))
+		}
+
+		if q.ForwardsResults {
+			call := s.forwardedCall(fnc)
+			if call == nil {
+				continue
+			}
+			details = append(details, "forwards "+types.ExprString(call.Args[0])+" to "+types.ExprString(call.Fun))
+		}
+
+		if q.DuplicateArgs {
+			dup := pq.duplicateParam(sig)
+			if dup == nil {
+				continue
+			}
+			details = append(details, "duplicate "+pq.typeString(dup))
+		}
+
+		if contextType != nil {
+			index := paramIndex(sig, contextType)
+			if index < 1 {
+				continue
+			}
+			details = append(details, fmt.Sprintf("context.Context is parameter %d", index+1))
+		}
+
+		key := fnc.Pkg.Path()
+		if constructors {
+			named := constructedType(fnc, sig)
+			if named == nil {
+				continue
+			}
+			if q.ZeroArgConstructors && sig.Params().Len() != 0 {
+				continue
+			}
+			key = pq.typeString(named)
+			confidence *= constructorConfidence(fnc, sig, named)
+		}
+
+		if pq.matchTypes(sig) {
+			if q.MinMatches > 0 {
+				details = append(details, fmt.Sprintf("matched %d of %d types", pq.matchedCount(sig), len(q.Args)+len(q.Rets)))
+			}
+			pos := s.position(fnc)
+			test := strings.HasSuffix(pos.Filename, "_test.go")
+			matches = append(matches, Match{key, fnc, sig, pq.via(sig), strings.Join(details, "; "), confidence, pos, s.docSummary(fnc), test, pq})
+		}
+	}
+
+	if err := scoped.err(); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// variadicElem returns the element type of sig's variadic parameter,
+// or nil if sig isn't variadic.
+func variadicElem(sig *types.Signature) types.Type {
+	if !sig.Variadic() {
+		return nil
+	}
+	params := sig.Params()
+	return params.At(params.Len() - 1).Type().(*types.Slice).Elem()
+}
+
+func isCatchAll(sig *types.Signature) bool {
+	elem := variadicElem(sig)
+	if elem == nil {
+		return false
+	}
+	iface, ok := elem.Underlying().(*types.Interface)
+	return ok && iface.NumMethods() == 0
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+func isError(typ types.Type) bool {
+	return types.Identical(typ, errorType)
+}
+
+// matchErrorResult reports whether the results of sig that implement
+// error satisfy want.
+func matchErrorResult(sig *types.Signature, want ErrorResult) bool {
+	iface := errorType.Underlying().(*types.Interface)
+	results := sig.Results()
+	switch want {
+	case ErrorLast:
+		return lastResultImplements(sig, iface)
+	case ErrorAny, ErrorNone:
+		found := false
+		for i := 0; i < results.Len(); i++ {
+			if types.Implements(results.At(i).Type(), iface) {
+				found = true
+				break
+			}
+		}
+		return found == (want == ErrorAny)
+	}
+	return true
+}
+
+// isCleanup reports whether typ is func() or func() error.
+func isCleanup(typ types.Type) bool {
+	sig, ok := typ.Underlying().(*types.Signature)
+	if !ok || sig.Params().Len() != 0 {
+		return false
+	}
+	results := sig.Results()
+	return results.Len() == 0 || (results.Len() == 1 && isError(results.At(0).Type()))
+}
+
+func hasCleanupResult(sig *types.Signature) bool {
+	var cleanup, value bool
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		typ := results.At(i).Type()
+		if isCleanup(typ) {
+			cleanup = true
+		} else if _, ok := typ.Underlying().(*types.Signature); !ok && !isError(typ) {
+			value = true
+		}
+	}
+	return cleanup && value
+}
+
+// forwardedCall returns the first call in fnc's body whose only
+// argument is another call returning multiple values.
+func (s *Snapshot) forwardedCall(fnc Func) *ast.CallExpr {
+	src, obj, ok := s.source(fnc)
+	if !ok {
+		return nil
+	}
+	decl := src.decls[obj]
+	if decl == nil || decl.Body == nil {
+		return nil
+	}
+	info := src.info
+
+	var found *ast.CallExpr
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || found != nil || len(call.Args) != 1 {
+			return found == nil
+		}
+		inner, ok := call.Args[0].(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if tuple, ok := info.Types[inner].Type.(*types.Tuple); ok && tuple.Len() > 1 {
+			found = call
+		}
+		return found == nil
+	})
+	return found
+}
+
+func hasDirective(decl *ast.FuncDecl, prefix string) bool {
+	if decl == nil || decl.Doc == nil {
+		return false
+	}
+	for _, c := range decl.Doc.List {
+		if strings.HasPrefix(c.Text, "//"+prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// methodCount returns the number of methods of typ, including those
+// only in the method set of *typ.
+func methodCount(typ types.Type) int {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if _, ok := typ.Underlying().(*types.Interface); !ok {
+		typ = types.NewPointer(typ)
+	}
+	return types.NewMethodSet(typ).Len()
+}
+
+// returnsAssignable reports whether one of sig's results is assignable
+// to any of targets, or to all of them if all is set.
+func returnsAssignable(sig *types.Signature, targets []types.Type, all bool) bool {
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		typ := results.At(i).Type()
+		n := 0
+		for _, target := range targets {
+			if types.AssignableTo(typ, target) {
+				n++
+			}
+		}
+		if (all && n == len(targets)) || (!all && n > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// selfConsistentPair finds a concrete result of sig that implements
+// another, interface-typed result.
+func selfConsistentPair(sig *types.Signature) (concrete, iface types.Type) {
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		typ := results.At(i).Type()
+		if _, ok := typ.Underlying().(*types.Interface); ok {
+			continue
+		}
+		for j := 0; j < results.Len(); j++ {
+			other := results.At(j).Type()
+			it, ok := other.Underlying().(*types.Interface)
+			if !ok || isError(other) || it.NumMethods() == 0 {
+				continue
+			}
+			if types.Implements(typ, it) {
+				return typ, other
+			}
+		}
+	}
+	return nil, nil
+}
+
+// countParamKinds counts the parameters of sig whose types are
+// concrete and interfaces.
+func countParamKinds(sig *types.Signature) (concrete, iface int) {
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := params.At(i).Type().Underlying().(*types.Interface); ok {
+			iface++
+		} else {
+			concrete++
+		}
+	}
+	return concrete, iface
+}
+
+// returnsRecvChan reports whether sig returns a channel that can be
+// received from and, if q.ProducerElem isn't empty, whose element
+// type matches it.
+func (q Query) returnsRecvChan(sig *types.Signature) bool {
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		ch, ok := results.At(i).Type().Underlying().(*types.Chan)
+		if !ok || ch.Dir() == types.SendOnly {
+			continue
+		}
+		if q.ProducerElem == "" || q.typeMatches(ch.Elem(), q.ProducerElem) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramIndex returns the index of the first parameter of sig with
+// type typ, or -1.
+func paramIndex(sig *types.Signature, typ types.Type) int {
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if types.Identical(params.At(i).Type(), typ) {
+			return i
+		}
+	}
+	return -1
+}
+
+// pairedErrorType returns the error type associated with the result
+// of a function returning (T, error) or (*T, error). The association
+// is purely by name: the error type's name has to start with T's.
+func pairedErrorType(sig *types.Signature) *types.TypeName {
+	results := sig.Results()
+	if results.Len() != 2 || !isError(results.At(1).Type()) {
+		return nil
+	}
+	typ := results.At(0).Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+
+	errIface := errorType.Underlying().(*types.Interface)
+	scope := named.Obj().Pkg().Scope()
+	for _, name := range scope.Names() {
+		if name == named.Obj().Name() || !strings.HasPrefix(name, named.Obj().Name()) {
+			continue
+		}
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if types.Implements(tn.Type(), errIface) || types.Implements(types.NewPointer(tn.Type()), errIface) {
+			return tn
+		}
+	}
+	return nil
+}
+
+// hasOutParam reports whether sig returns nothing or only an error and
+// takes a pointer parameter whose element type matches q.Args, if
+// any.
+func (q Query) hasOutParam(sig *types.Signature) bool {
+	results := sig.Results()
+	if results.Len() > 1 || (results.Len() == 1 && !isError(results.At(0).Type())) {
+		return false
+	}
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		ptr, ok := params.At(i).Type().(*types.Pointer)
+		if !ok {
+			continue
+		}
+		if len(q.Args) == 0 || q.Matcher(q.Args)(ptr.Elem()) {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateParam returns the first type that occurs more than once
+// among sig's parameters and matches q.Args, if any are set.
+func (q Query) duplicateParam(sig *types.Signature) types.Type {
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		typ := params.At(i).Type()
+		if len(q.Args) > 0 && !q.Matcher(q.Args)(typ) {
+			continue
+		}
+		for j := i + 1; j < params.Len(); j++ {
+			if types.Identical(typ, params.At(j).Type()) {
+				return typ
+			}
+		}
+	}
+	return nil
+}
+
+// matchSingleResult checks sig against the ReturnsPointer,
+// ReturnsErrorOnly, ReturnsBool and ReturnsString shorthands.
+func (q Query) matchSingleResult(sig *types.Signature) bool {
+	if sig.Results().Len() != 1 {
+		return false
+	}
+	typ := sig.Results().At(0).Type()
+	if _, ok := typ.(*types.Pointer); q.ReturnsPointer && !ok {
+		return false
+	}
+	if q.ReturnsErrorOnly && !isError(typ) {
+		return false
+	}
+	if q.ReturnsBool && !types.Identical(typ, types.Typ[types.Bool]) {
+		return false
+	}
+	if q.ReturnsString && !types.Identical(typ, types.Typ[types.String]) {
+		return false
+	}
+	return true
+}
+
+// confidence turns the number of optional sub-criteria of a heuristic
+// that were met into a confidence. Meeting just the required criteria
+// is better than nothing, meeting all of them is a certain match.
+func confidence(met, total int) float64 {
+	return float64(1+met) / float64(1+total)
+}
+
+// constructorConfidence rates a function already known to be a
+// constructor of named by whether it is named after the type and
+// whether it returns nothing but the value and possibly an error.
+func constructorConfidence(fnc Func, sig *types.Signature, named *types.Named) float64 {
+	met := 0
+	if fnc.Name() == "New" || fnc.Name() == "New"+named.Obj().Name() {
+		met++
+	}
+	results := sig.Results()
+	if results.Len() == 1 || (results.Len() == 2 && isError(results.At(1).Type())) {
+		met++
+	}
+	return confidence(met, 2)
+}
+
+// outParamNames are parameter names that suggest an out-parameter.
+var outParamNames = map[string]bool{
+	"dst": true, "dest": true, "out": true, "v": true, "res": true, "result": true, "ptr": true,
+}
+
+// outParamConfidence rates a function already known to have an
+// out-parameter by whether it reports errors and whether any pointer
+// parameter is named like an out-parameter.
+func outParamConfidence(sig *types.Signature) float64 {
+	met := 0
+	if sig.Results().Len() == 1 {
+		met++
+	}
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := params.At(i).Type().(*types.Pointer); ok && outParamNames[params.At(i).Name()] {
+			met++
+			break
+		}
+	}
+	return confidence(met, 2)
+}
+
+// tupleHasName reports whether an entry of tuple has a name matching
+// the glob pattern. Unnamed entries never match.
+func tupleHasName(tuple *types.Tuple, pattern string) (bool, error) {
+	for i := 0; i < tuple.Len(); i++ {
+		name := noDot(tuple.At(i).Name())
+		if name == "" || name == "_" {
+			continue
+		}
+		if ok, err := path.Match(pattern, name); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,958 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadTest(t testing.TB, paths ...string) *Snapshot {
+	s := Load(newTestContext(t), paths)
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	return s
+}
+
+// matchTest is a query and the names of the functions it should
+// match.
+type matchTest struct {
+	name  string
+	query Query
+	want  []string
+}
+
+// testMatches runs tests against the testdata packages paths.
+func testMatches(t *testing.T, tests []matchTest, paths ...string) {
+	s := loadTest(t, paths...)
+	for _, tt := range tests {
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if got := matchNames(matches); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReturnsErrorType(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"custom interface", Query{ReturnsErrorType: "errs.Error"}, []string{"Coded", "Iface"}},
+		{"builtin error", Query{ReturnsErrorType: "error"}, []string{"Coded", "Iface", "Plain"}},
+	}, "errs")
+}
+
+func TestPredeclaredError(t *testing.T) {
+	// error isn't declared in any package, but type-based modes have
+	// to resolve it all the same.
+	testMatches(t, []matchTest{
+		{"assignable", Query{Rets: []string{"error"}, Assignable: true}, []string{"Coded", "Iface", "NotLast", "Plain"}},
+		{"assignable to", Query{AssignableTo: []string{"error"}}, []string{"Coded", "Iface", "NotLast", "Plain"}},
+	}, "errs")
+
+	s := loadTest(t, "errs")
+	matches, err := s.Types(TypeQuery{Implements: []string{"error"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Named.Obj().Name())
+	}
+	if want := []string{"CodeError", "Error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got types %v, want %v", got, want)
+	}
+}
+
+func TestFuncVars(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"any kind", Query{Args: []string{"io.Reader"}}, []string{"Handle", "Hook"}},
+		{"funcs", Query{Args: []string{"io.Reader"}, Kind: KindFunc}, []string{"Handle", "Hook"}},
+		{"methods", Query{Args: []string{"io.Reader"}, Kind: KindMethod}, nil},
+	}, "funcvars")
+
+	s := loadTest(t, "funcvars")
+	matches, err := s.Match(Query{Name: "Hook"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %v, want Hook", matchNames(matches))
+	}
+	if got, want := FormatSignature(matches[0], FormatOptions{}), "var Hook(io.Reader) (error)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"constructors", Query{Constructors: true}, []string{"New", "NewWithConfig"}},
+		{"zero-arg constructors", Query{ZeroArgConstructors: true}, []string{"New"}},
+	}, "ctors")
+
+	// Constructors are grouped by the type they construct.
+	s := loadTest(t, "ctors")
+	matches, err := s.Match(Query{Constructors: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range matches {
+		if m.Key != "ctors.Client" {
+			t.Errorf("%s has key %s, want ctors.Client", m.Func.Name(), m.Key)
+		}
+	}
+}
+
+func TestDeps(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"one package", Query{MinDeps: 1}, []string{"Local", "One", "Three", "Two"}},
+		{"two packages", Query{MinDeps: 2}, []string{"Three", "Two"}},
+		{"three packages", Query{MinDeps: 3}, []string{"Three"}},
+		{"uses time", Query{UsesPkg: "time"}, []string{"Three", "Two"}},
+		{"uses deps", Query{UsesPkg: "deps"}, []string{"Local"}},
+		{"three packages, one of them time", Query{MinDeps: 3, UsesPkg: "time"}, []string{"Three"}},
+	}, "deps")
+}
+
+func TestReturnsPtrImplementing(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"closers", Query{ReturnsPtrImplementing: "io.Closer"}, []string{"NewFile", "NewValue", "Open"}},
+		{"constructors of closers", Query{ReturnsPtrImplementing: "io.Closer", Constructors: true}, []string{"NewFile", "NewValue"}},
+	}, "closers")
+}
+
+func TestCleanVendor(t *testing.T) {
+	tests := []struct {
+		clean bool
+		typ   string
+		ret   string
+		sig   string
+	}{
+		{true, "*example.org/lib.T", "example.org/lib.T", "Use(t *example.org/lib.T) (example.org/lib.T)"},
+		{false, "*vend/vendor/example.org/lib.T", "vend/vendor/example.org/lib.T", "Use(t *vend/vendor/example.org/lib.T) (vend/vendor/example.org/lib.T)"},
+	}
+	for _, tt := range tests {
+		ctx := newTestContext(t)
+		ctx.CleanVendor = tt.clean
+		s := Load(ctx, []string{"vend"})
+		matches, err := s.Match(Query{Args: []string{tt.typ}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 {
+			t.Errorf("CleanVendor = %t: %s matched %v, want Use", tt.clean, tt.typ, matchNames(matches))
+			continue
+		}
+		if got := FormatSignature(matches[0], FormatOptions{}); got != tt.sig {
+			t.Errorf("CleanVendor = %t: got %q, want %q", tt.clean, got, tt.sig)
+		}
+		if got := s.TypeString(matches[0].Sig.Results().At(0).Type()); got != tt.ret {
+			t.Errorf("CleanVendor = %t: TypeString returned %q, want %q", tt.clean, got, tt.ret)
+		}
+	}
+}
+
+func TestCatchAll(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"catch-all", Query{CatchAll: true}, []string{"Anyf", "Logf", "Println"}},
+		{"catch-all named *f", Query{CatchAll: true, Name: "*f"}, []string{"Anyf", "Logf"}},
+	}, "variadic")
+}
+
+func TestReturnsCleanup(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"cleanup", Query{ReturnsCleanup: true}, []string{"Setup", "SetupErr"}},
+		{"cleanup and error", Query{ReturnsCleanup: true, ReturnsError: ErrorLast}, []string{"Setup"}},
+	}, "cleanup")
+}
+
+func TestDirective(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"go:noinline", Query{Directive: "go:noinline"}, []string{"Hot"}},
+		{"go:nosplit", Query{Directive: "go:nosplit"}, []string{"Cold"}},
+		{"any go: directive", Query{Directive: "go:"}, []string{"Cold", "Hot"}},
+		{"unused directive", Query{Directive: "go:linkname"}, nil},
+	}, "directives")
+}
+
+func TestMinMethods(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"one method", Query{MinMethods: 1}, []string{"Len", "Put", "Reset", "Get"}},
+		{"three methods", Query{MinMethods: 3}, []string{"Len", "Put", "Reset"}},
+		{"four methods", Query{MinMethods: 4}, nil},
+	}, "methods")
+}
+
+func TestAssignableTo(t *testing.T) {
+	targets := []string{"io.Reader", "io.Closer"}
+	testMatches(t, []matchTest{
+		{"any", Query{AssignableTo: targets}, []string{"OpenReadCloser", "OpenReader"}},
+		{"all", Query{AssignableTo: targets, AssignableToAll: true}, []string{"OpenReadCloser"}},
+	}, "assignto")
+}
+
+func TestSelfConsistentReturns(t *testing.T) {
+	s := loadTest(t, "selfcons")
+	matches, err := s.Match(Query{SelfConsistentReturns: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := matchNames(matches); !reflect.DeepEqual(got, []string{"Both"}) {
+		t.Fatalf("got %v, want [Both]", got)
+	}
+	if want := "*selfcons.Impl implements selfcons.Iface"; matches[0].Detail != want {
+		t.Errorf("got detail %q, want %q", matches[0].Detail, want)
+	}
+}
+
+func TestVariadicOf(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"...string", Query{VariadicOf: "string"}, []string{"Join"}},
+		{"...int", Query{VariadicOf: "int"}, []string{"Sum"}},
+		{"...interface{}", Query{VariadicOf: "interface{}"}, []string{"Logf", "Println"}},
+		{"variadic", Query{Variadic: true}, []string{"Anyf", "Join", "Logf", "Println", "Sum"}},
+		// The element type is matched like Args.
+		{"local type", Query{VariadicOf: "Any"}, []string{"Anyf"}},
+		{"underlying", Query{VariadicOf: "interface{}", Underlying: true}, []string{"Anyf", "Logf", "Println"}},
+		{"regex", Query{VariadicOf: "str.*", Regex: true}, []string{"Join"}},
+	}, "variadic")
+}
+
+func TestResolveTargets(t *testing.T) {
+	s := loadTest(t, "resolve")
+	tests := []struct {
+		query Query
+		want  []string
+	}{
+		{Query{Rets: []string{"map[string]int"}, Assignable: true}, []string{"Map"}},
+		{Query{Rets: []string{"<-chan int"}, Assignable: true}, []string{"Chan"}},
+		{Query{Rets: []string{"func(io.Reader) error"}, Assignable: true}, []string{"Func", "Handler"}},
+		{Query{Rets: []string{"func(r io.Reader) error"}, Underlying: true}, []string{"Func", "Handler"}},
+		{Query{Rets: []string{"struct{ X int }"}, Underlying: true}, []string{"Struct"}},
+		{Query{Rets: []string{"[4]byte"}, Underlying: true}, []string{"Array"}},
+		{Query{Rets: []string{"map[string][]chan<- bool"}, Underlying: true}, []string{"Nested"}},
+		{Query{Rets: []string{"interface{}"}, Underlying: true}, []string{"Empty"}},
+		{Query{Rets: []string{"interface{ Read([]byte) (int, error) }"}, Underlying: true}, []string{"Reader"}},
+		// Unexported types of the searched packages resolve once
+		// queries are scoped to them.
+		{Query{Rets: []string{"*local"}, Assignable: true}, []string{"Local"}},
+		{Query{Rets: []string{"handler"}, Assignable: true}, []string{"Func", "Handler"}},
+	}
+	for _, tt := range tests {
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Errorf("%v: %s", tt.query.Rets, err)
+			continue
+		}
+		if got := matchNames(matches); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.query.Rets, got, tt.want)
+		}
+	}
+}
+
+func TestResolveTargetsErrors(t *testing.T) {
+	s := loadTest(t, "resolve")
+	for _, target := range []string{"undeclared", "io.Undeclared", "func(", "[x]int", "map[string"} {
+		if _, err := s.Match(Query{Rets: []string{target}, Assignable: true}); err == nil {
+			t.Errorf("%s: got no error", target)
+		}
+	}
+}
+
+func TestExprScoped(t *testing.T) {
+	s := loadTest(t, "resolve")
+	tests := []struct {
+		expr  string
+		query Query
+		want  []string
+	}{
+		{"ret:*local", Query{}, []string{"Local"}},
+		{"ret:handler & !ret:*local", Query{Underlying: true}, []string{"Func", "Handler"}},
+		{"ret:*local | ret:map[string]int", Query{Assignable: true}, []string{"Local", "Map"}},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.query.Expr = expr
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Errorf("%s: %s", tt.expr, err)
+			continue
+		}
+		if got := matchNames(matches); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestExprIncompatible(t *testing.T) {
+	s := loadTest(t, "resolve")
+	expr, err := ParseExpr("ret:int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []Query{
+		{Expr: expr, Positional: true},
+		{Expr: expr, SameArg: true},
+		{Expr: expr, MinMatches: 1},
+		{Expr: expr, NArgs: &Range{Min: 0, Max: 1}},
+		{Expr: expr, NRets: &Range{Min: 1, Max: 1}},
+	} {
+		if _, err := s.Match(q); err == nil {
+			t.Errorf("%+v: got no error", q)
+		}
+	}
+}
+
+func TestPromotedPosition(t *testing.T) {
+	for _, paths := range [][]string{
+		{"example.org/promoted/outer"},
+		{"example.org/promoted/outer", "example.org/promoted/inner"},
+		{"example.org/promoted/inner", "example.org/promoted/outer"},
+	} {
+		s := loadTest(t, paths...)
+		matches, err := s.Match(Query{Name: "Close", Promoted: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range matches {
+			pos, doc := m.Pos, m.Doc
+			if m.PromotedFrom() == "" {
+				// Declared by inner.Inner itself.
+				continue
+			}
+			if len(paths) == 1 {
+				// example.org/promoted/inner was only imported, not
+				// type-checked from source.
+				if pos.IsValid() || doc != "" {
+					t.Errorf("%v: got position %s and doc %q, want none", paths, pos, doc)
+				}
+				continue
+			}
+			if filepath.Base(pos.Filename) != "inner.go" || pos.Line != 6 || doc != "Close closes the Inner." {
+				t.Errorf("%v: got position %s and doc %q, want inner.go:6", paths, pos, doc)
+			}
+		}
+		if len(matches) != len(paths) {
+			t.Errorf("%v: got %d matches, want %d", paths, len(matches), len(paths))
+		}
+	}
+}
+
+func TestNoPromotedStdlib(t *testing.T) {
+	s := loadTest(t, "example.org/promoted/outer")
+	tests := []struct {
+		query Query
+		want  map[string]bool
+	}{
+		{Query{Kind: KindMethod}, map[string]bool{"Open": true}},
+		{Query{Kind: KindMethod, Promoted: true}, map[string]bool{"Open": true, "Close": true, "Lock": true, "Unlock": true}},
+		{Query{Kind: KindMethod, NoPromotedStdlib: true}, map[string]bool{"Open": true, "Close": true}},
+		{Query{Kind: KindMethod, Promoted: true, NoPromotedStdlib: true}, map[string]bool{"Open": true, "Close": true}},
+	}
+	for _, tt := range tests {
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for _, name := range matchNames(matches) {
+			got[name] = true
+		}
+		for name := range tt.want {
+			if !got[name] {
+				t.Errorf("%+v: %s didn't match", tt.query, name)
+			}
+		}
+		for name := range got {
+			// Newer versions of sync.Mutex have more methods,
+			// such as TryLock.
+			if !tt.want[name] && (tt.query.NoPromotedStdlib || !tt.query.Promoted) {
+				t.Errorf("%+v: %s matched", tt.query, name)
+			}
+		}
+	}
+}
+
+func TestSnapshotQueries(t *testing.T) {
+	s := loadTest(t, "resolve")
+	queries := []Query{
+		{Rets: []string{"map[string]int"}},
+		{Rets: []string{"io.Reader"}, Assignable: true},
+		{Rets: []string{"*local"}, Assignable: true},
+		{Name: "*", Kind: KindFunc},
+	}
+	want := make([][]string, len(queries))
+	for i, q := range queries {
+		matches, err := s.Match(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[i] = matchNames(matches)
+	}
+
+	// Matching again, concurrently, yields the same results.
+	errs := make(chan error, len(queries))
+	for i, q := range queries {
+		go func(i int, q Query) {
+			matches, err := s.Match(q)
+			if err == nil && !reflect.DeepEqual(matchNames(matches), want[i]) {
+				err = fmt.Errorf("%+v: got %v, want %v", q, matchNames(matches), want[i])
+			}
+			errs <- err
+		}(i, q)
+	}
+	for range queries {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestSnapshotConcurrentLoad(t *testing.T) {
+	ctx := newTestContext(t)
+	ctx.Build.GOOS = "linux"
+	s := Load(ctx, []string{"resolve"})
+	q := Query{Name: "*", Assignable: true, Rets: []string{"*local"}}
+	want, err := s.Match(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Load(ctx, []string{"example.org/promoted/outer", "platform/a", "resolve"})
+	}()
+	for i := 0; i < 10; i++ {
+		got, err := s.Match(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", matchNames(got), matchNames(want))
+		}
+		for j := range got {
+			if got[j].Func.Object != want[j].Func.Object || got[j].Pos != want[j].Pos {
+				t.Errorf("got %s at %s, want %s at %s", got[j].Func.Name(), got[j].Pos, want[j].Func.Name(), want[j].Pos)
+			}
+		}
+	}
+	<-done
+
+	// Reloading a package doesn't change the snapshots of earlier
+	// loads.
+	got, err := s.Match(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Func.Object != want[0].Func.Object {
+		t.Errorf("got %v after reloading, want %v", matchNames(got), matchNames(want))
+	}
+}
+
+func TestFuncRecv(t *testing.T) {
+	s := loadTest(t, "example.org/promoted/outer", "resolve")
+	matches, err := s.Match(Query{Name: "*", Promoted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Open":  "*example.org/promoted/outer.Outer",
+		"Close": "example.org/promoted/outer.Outer",
+		"Lock":  "*example.org/promoted/outer.Outer",
+		"Map":   "",
+	}
+	for _, m := range matches {
+		recv, ok := want[m.Func.Name()]
+		if !ok {
+			continue
+		}
+		got := ""
+		if typ := m.Func.Recv(); typ != nil {
+			got = TypeString(typ)
+		}
+		if got != recv {
+			t.Errorf("%s: got receiver %q, want %q", m.Func.Name(), got, recv)
+		}
+		if pos := s.Position(m); pos != m.Pos {
+			t.Errorf("%s: Position = %s, want %s", m.Func.Name(), pos, m.Pos)
+		}
+		delete(want, m.Func.Name())
+	}
+	for name := range want {
+		t.Errorf("%s didn't match", name)
+	}
+}
+
+func TestMatchTypesFastPath(t *testing.T) {
+	s := loadTest(t, "bytes", "io", "strings", "resolve")
+	queries := []Query{
+		{Args: []string{"string"}},
+		{Args: []string{"[]byte"}},
+		{Args: []string{"_"}},
+		{Args: []string{"io.Reader"}, Assignable: true},
+		{Args: []string{"byte"}, IgnoreCase: true},
+		{Rets: []string{"error"}},
+		{Rets: []string{"int"}},
+		{Rets: []string{"io.Writer"}, Underlying: true},
+		{Rets: []string{"map[string]_"}},
+	}
+	for _, q := range queries {
+		scoped := s.newScopedQueries(q)
+		n := 0
+		for _, fnc := range s.funcs {
+			sig, ok := fnc.Type().Underlying().(*types.Signature)
+			if !ok {
+				continue
+			}
+			pq, ok := scoped.get(fnc.Pkg)
+			if !ok {
+				t.Fatalf("%+v: %s", q, scoped.err())
+			}
+			fast, slow := pq.matchTypes(sig), pq.matchAnyAll(sig)
+			if fast != slow {
+				t.Errorf("%v %v: fast path says %t, slow path %t for %s", q.Args, q.Rets, fast, slow, fnc.Name())
+			}
+			if fast {
+				n++
+			}
+		}
+		if n == 0 {
+			t.Errorf("%v %v: nothing matched", q.Args, q.Rets)
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	s := loadTest(b, "bytes", "io", "strings")
+	queries := []Query{
+		{Args: []string{"string"}},
+		{Args: []string{"string", "int"}},
+		{Args: []string{"io.Reader"}, Assignable: true},
+	}
+	for _, q := range queries {
+		b.Run(strings.Join(q.Args, ","), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := s.Match(q); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestParamKinds(t *testing.T) {
+	n := func(n int) *int { return &n }
+	testMatches(t, []matchTest{
+		{"one of each", Query{ConcreteArgs: n(1), InterfaceArgs: n(1)}, []string{"Serve"}},
+		{"interfaces only", Query{ConcreteArgs: n(0), InterfaceArgs: n(2)}, []string{"Pipe"}},
+		{"concrete only", Query{InterfaceArgs: n(0), ConcreteArgs: n(2)}, []string{"Values"}},
+		{"empty interface", Query{ConcreteArgs: n(0), InterfaceArgs: n(1)}, []string{"Load", "Print"}},
+		{"no parameters", Query{ConcreteArgs: n(0), InterfaceArgs: n(0)}, []string{"Empty"}},
+		{"concrete count", Query{ConcreteArgs: n(2)}, []string{"Copy", "Values"}},
+		{"interface count", Query{InterfaceArgs: n(1)}, []string{"Load", "Copy", "Print", "Serve"}},
+		{"with arity", Query{InterfaceArgs: n(1), NArgs: &Range{Min: 1, Max: 1}}, []string{"Load", "Print"}},
+	}, "argkinds")
+}
+
+func TestProducers(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"producers", Query{Producers: true}, []string{"Both", "Events", "Ints", "Lines", "Stream"}},
+		{"element type", Query{Producers: true, ProducerElem: "int"}, []string{"Both", "Ints"}},
+		{"struct{} element", Query{Producers: true, ProducerElem: "struct{}"}, []string{"Stream"}},
+		{"with error", Query{Producers: true, ReturnsError: ErrorLast}, []string{"Lines"}},
+		{"without error", Query{Producers: true, ReturnsError: ErrorNone}, []string{"Both", "Events", "Ints", "Stream"}},
+		{"unknown element", Query{Producers: true, ProducerElem: "float64"}, nil},
+		{"local element", Query{Producers: true, ProducerElem: "Event"}, []string{"Events"}},
+		{"assignable element", Query{Producers: true, ProducerElem: "interface{}", Assignable: true}, []string{"Both", "Events", "Ints", "Lines", "Stream"}},
+	}, "producers")
+}
+
+func TestUnexportedTypes(t *testing.T) {
+	// Unexported types resolve in the scope of each searched package,
+	// so that cache refers to a different type in a and b.
+	testMatches(t, []matchTest{
+		{"args", Query{Args: []string{"cache"}}, []string{"Use", "merge", "Fill"}},
+		{"rets", Query{Rets: []string{"*cache"}}, []string{"newCache"}},
+		{"assignable", Query{Args: []string{"cache"}, Assignable: true}, []string{"Use", "merge", "Fill"}},
+		{"qualified", Query{Args: []string{"private/b.cache"}}, []string{"Fill"}},
+	}, "private/a", "private/b")
+}
+
+func TestStructByType(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"by name", Query{Rets: []string{"struct{ Y int }"}}, nil},
+		{"by type", Query{Rets: []string{"struct{ Y int }"}, StructByType: true}, []string{"Point", "Tagged"}},
+		{"several fields", Query{Rets: []string{"struct{ C string; D string }"}, StructByType: true}, []string{"Pair"}},
+		{"field order", Query{Rets: []string{"struct{ C string; D int }"}, StructByType: true}, nil},
+		{"nested", Query{Rets: []string{"map[string]struct{ M int }"}, StructByType: true}, []string{"Nested"}},
+		{"args", Query{Args: []string{"struct{ Q int }"}, StructByType: true}, []string{"Take"}},
+	}, "anonstructs")
+}
+
+// testDetails checks that q matches the functions in the testdata
+// package path that are keys of want, with the details in want.
+func testDetails(t *testing.T, q Query, want map[string]string, path string) {
+	matches, err := loadTest(t, path).Match(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, m := range matches {
+		got[m.Func.Name()] = m.Detail
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestContextNotFirst(t *testing.T) {
+	testDetails(t, Query{ContextNotFirst: true}, map[string]string{
+		"Do":    "context.Context is parameter 2",
+		"Late":  "context.Context is parameter 2",
+		"Third": "context.Context is parameter 3",
+	}, "ctxpos")
+}
+
+func TestResultErrorPairing(t *testing.T) {
+	testDetails(t, Query{ResultErrorPairing: true}, map[string]string{
+		"GetItem": "errors: pairing.ItemErr",
+		"GetUser": "errors: pairing.UserError",
+	}, "pairing")
+}
+
+func TestForwardsResults(t *testing.T) {
+	testDetails(t, Query{ForwardsResults: true}, map[string]string{
+		"Return": "forwards pair() to use",
+		"Stmt":   "forwards pair() to use",
+	}, "forwarding")
+}
+
+func TestAssignableSig(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"handler", Query{AssignableSig: "sigs/api.HandlerFunc"}, []string{"Serve", "Handle"}},
+		{"free functions", Query{AssignableSig: "sigs/api.HandlerFunc", Kind: KindFunc}, []string{"Serve"}},
+	}, "sigs")
+
+	s := loadTest(t, "sigs")
+	if _, err := s.Match(Query{AssignableSig: "sigs/api.Request"}); err == nil {
+		t.Error("got no error for a struct type")
+	}
+}
+
+func TestResolvedCache(t *testing.T) {
+	ctx := newTestContext(t)
+	log := &recordLogger{}
+	ctx.Log = log
+	s := Load(ctx, []string{"errs"})
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	q := Query{Rets: []string{"errs.Error"}, Assignable: true}
+	hits := func() int {
+		n := 0
+		for _, msg := range log.debug {
+			if msg == "Resolved errs.Error from cache" {
+				n++
+			}
+		}
+		return n
+	}
+	for i, want := range []int{0, 1} {
+		matches, err := s.Match(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matchNames(matches); !reflect.DeepEqual(got, []string{"Coded", "Iface", "NotLast"}) {
+			t.Errorf("query %d: got %v, want [Coded Iface NotLast]", i+1, got)
+		}
+		if got := hits(); got != want {
+			t.Errorf("query %d: got %d cache hits, want %d", i+1, got, want)
+		}
+	}
+
+	// Loading packages again starts over with an empty cache.
+	s = Load(ctx, []string{"errs"})
+	log.debug = nil
+	if _, err := s.Match(q); err != nil {
+		t.Fatal(err)
+	}
+	if got := hits(); got != 0 {
+		t.Errorf("got %d cache hits after reloading, want 0", got)
+	}
+}
+
+func TestResolvedCacheModes(t *testing.T) {
+	s := loadTest(t, "resolve")
+	all := []string{"Array", "Chan", "Empty", "Func", "Handler", "Local", "Map", "Nested", "Reader", "Struct"}
+	tests := []struct {
+		query Query
+		want  []string
+	}{
+		{Query{Rets: []string{"interface{}"}, Underlying: true}, []string{"Empty"}},
+		{Query{Rets: []string{"interface{}"}, Assignable: true}, all},
+		{Query{Rets: []string{"interface{}"}}, []string{"Empty"}},
+		{Query{Rets: []string{"interface{}"}, Underlying: true}, []string{"Empty"}},
+		{Query{Rets: []string{"interface{}"}, Assignable: true}, all},
+	}
+	for i, tt := range tests {
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matchNames(matches); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("query %d: got %v, want %v", i+1, got, tt.want)
+		}
+	}
+}
+
+func TestOutParams(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"out params", Query{OutParams: true}, []string{"Decode", "Fill", "Load"}},
+		{"pointee", Query{OutParams: true, Args: []string{"outparams.Config"}}, []string{"Decode", "Load"}},
+		{"slice pointee", Query{OutParams: true, Args: []string{"[]string"}}, []string{"Fill"}},
+		{"unknown pointee", Query{OutParams: true, Args: []string{"int"}}, nil},
+	}, "outparams")
+}
+
+func TestHasBody(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"has body", Query{HasBody: true, Args: []string{"int"}}, []string{"Sub"}},
+		{"external only", Query{ExternalOnly: true, Args: []string{"int"}}, []string{"Add"}},
+		{"either", Query{Args: []string{"int"}}, []string{"Add", "Sub"}},
+	}, "asm")
+}
+
+func TestDuplicateArgs(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"any type", Query{DuplicateArgs: true}, []string{"Copy", "Mixed", "Move", "Rename"}},
+		{"int", Query{DuplicateArgs: true, Args: []string{"int"}}, []string{"Mixed", "Move"}},
+		{"string", Query{DuplicateArgs: true, Args: []string{"string"}}, []string{"Rename"}},
+		{"bool", Query{DuplicateArgs: true, Args: []string{"bool"}}, nil},
+	}, "dupargs")
+
+	s := loadTest(t, "dupargs")
+	matches, err := s.Match(Query{DuplicateArgs: true, Name: "Copy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %v, want Copy", matchNames(matches))
+	}
+	if got, want := matches[0].Detail, "duplicate []byte"; got != want {
+		t.Errorf("got detail %q, want %q", got, want)
+	}
+}
+
+func TestSingleResult(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"pointer", Query{ReturnsPointer: true}, []string{"Ptr"}},
+		{"error only", Query{ReturnsErrorOnly: true}, []string{"Err"}},
+		{"bool", Query{ReturnsBool: true}, []string{"IsSet", "OK"}},
+		{"string", Query{ReturnsString: true}, []string{"Name"}},
+		{"bool with arity", Query{ReturnsBool: true, NArgs: &Range{Min: 0, Max: 0}}, []string{"OK"}},
+		{"bool with name", Query{ReturnsBool: true, Name: "Is*"}, []string{"IsSet"}},
+		{"contradictory", Query{ReturnsBool: true, ReturnsString: true}, nil},
+	}, "single")
+}
+
+func TestAssignableArgs(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"exact", Query{Args: []string{"io.Reader"}}, []string{"FromReader"}},
+		// Only *File implements io.Reader, and any io.ReadCloser is
+		// an io.Reader.
+		{"assignable", Query{Args: []string{"io.Reader"}, Assignable: true}, []string{"FromCloser", "FromFile", "FromReader"}},
+	}, "readers")
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		query Query
+		want  map[string]float64
+	}{
+		// New is named after the type it constructs, NewWithConfig
+		// isn't.
+		{"constructors", "ctors", Query{Constructors: true}, map[string]float64{"New": 1, "NewWithConfig": 2.0 / 3}},
+		// Decode returns an error and names its out-parameter dst,
+		// Fill only does the latter and Load neither.
+		{"out params", "outparams", Query{OutParams: true}, map[string]float64{"Decode": 1, "Fill": 2.0 / 3, "Load": 1.0 / 3}},
+		// Filters that aren't heuristics are always certain.
+		{"crisp", "outparams", Query{Rets: []string{"error"}}, map[string]float64{"Count": 1, "Decode": 1, "Parse": 1, "Read": 1}},
+	}
+	for _, tt := range tests {
+		s := loadTest(t, tt.path)
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		got := make(map[string]float64)
+		for _, m := range matches {
+			got[m.Func.Name()] = m.Confidence
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChanDir(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"receive-only", Query{Rets: []string{"<-chan int"}}, []string{"Recv"}},
+		{"send-only", Query{Rets: []string{"chan<- int"}}, []string{"Send"}},
+		{"bidirectional", Query{Rets: []string{"chan int"}}, []string{"Both"}},
+		{"any direction", Query{Rets: []string{"chan int"}, AnyChanDir: true}, []string{"Both", "Recv", "Send"}},
+		// AnyChanDir only widens bidirectional channel types.
+		{"directed with any direction", Query{Rets: []string{"<-chan int"}, AnyChanDir: true}, []string{"Recv"}},
+		{"any element", Query{Rets: []string{"<-chan _"}}, []string{"Recv", "Texts"}},
+		{"other element", Query{Rets: []string{"<-chan bool"}}, nil},
+	}, "matching")
+}
+
+func TestMapTypes(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"exact", Query{Rets: []string{"map[string]int"}}, []string{"Counts"}},
+		{"any element", Query{Rets: []string{"map[string]_"}}, []string{"Counts", "Names"}},
+		{"any key", Query{Rets: []string{"map[_]string"}}, []string{"Index", "Names"}},
+		{"any key and element", Query{Rets: []string{"map[_]_"}}, []string{"Counts", "Index", "Names"}},
+		{"no match", Query{Rets: []string{"map[bool]_"}}, nil},
+	}, "matching")
+}
+
+func TestSameArg(t *testing.T) {
+	args := []string{"io.Reader", "io.Closer"}
+	testMatches(t, []matchTest{
+		{"and", Query{Args: args, And: true, Assignable: true}, []string{"ReadAndClose", "ReadClose"}},
+		// ReadAndClose needs two parameters to satisfy both.
+		{"same arg", Query{Args: args, SameArg: true, Assignable: true}, []string{"ReadClose"}},
+		{"same arg, one type", Query{Args: []string{"io.Reader"}, SameArg: true, Assignable: true}, []string{"ReadAndClose", "ReadClose", "ReadOnly"}},
+		{"same arg, unsatisfiable", Query{Args: []string{"io.Closer", "io.Writer"}, SameArg: true, Assignable: true}, nil},
+	}, "matching")
+}
+
+func TestPositional(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"placeholder first", Query{Args: []string{AnyType, "string"}, Positional: true}, []string{"Pair"}},
+		{"placeholder second", Query{Args: []string{"string", AnyType}, Positional: true}, []string{"Swap"}},
+		{"placeholders", Query{Args: []string{AnyType, AnyType}, Positional: true}, []string{"Pair", "ReadAndClose", "Swap"}},
+		// The number of parameters has to match too.
+		{"too few", Query{Args: []string{"int"}, Positional: true}, nil},
+		{"args and rets", Query{Args: []string{"int", AnyType}, Rets: []string{"bool"}, Positional: true, And: true}, []string{"Pair"}},
+		// Without Positional, _ matches anything with a parameter.
+		{"placeholder as a set", Query{Args: []string{AnyType}}, []string{"Pair", "ReadAndClose", "ReadClose", "ReadOnly", "Swap"}},
+	}, "matching")
+}
+
+func TestMinMatches(t *testing.T) {
+	args := []string{"io.Reader", "io.Closer", "int", "string"}
+	testMatches(t, []matchTest{
+		{"any", Query{Args: args}, []string{"Pair", "ReadAndClose", "ReadOnly", "Swap"}},
+		{"two", Query{Args: args, MinMatches: 2}, []string{"Pair", "ReadAndClose", "Swap"}},
+		{"three", Query{Args: args, MinMatches: 3}, nil},
+		{"three with rets", Query{Args: args, Rets: []string{"bool"}, MinMatches: 3}, []string{"Pair"}},
+	}, "matching")
+}
+
+func TestRegex(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"alternation", Query{Rets: []string{`<-chan (int|string)`}, Regex: true}, []string{"Recv", "Texts"}},
+		{"either interface", Query{Args: []string{`io\.Read(er|Closer)`}, Regex: true}, []string{"ReadAndClose", "ReadClose", "ReadOnly"}},
+		// Expressions have to match the entire type.
+		{"anchored", Query{Args: []string{`io\.Read`}, Regex: true}, nil},
+		{"literal without regex", Query{Args: []string{`io\.Reader`}}, nil},
+	}, "matching")
+
+	s := loadTest(t, "matching")
+	if _, err := s.Match(Query{Args: []string{"("}, Regex: true}); err == nil {
+		t.Error("got no error for an invalid expression")
+	}
+}
+
+func TestUnqualified(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"qualified", Query{Args: []string{"*bytes.Buffer"}}, []string{"Fill"}},
+		{"unqualified", Query{Args: []string{"*Buffer"}, Unqualified: true}, []string{"Fill", "Local"}},
+		// Any qualification is dropped, not just the package's.
+		{"other package", Query{Args: []string{"*strings.Buffer"}, Unqualified: true}, []string{"Fill", "Local"}},
+		{"pointer still matters", Query{Args: []string{"Buffer"}, Unqualified: true}, []string{"Grow"}},
+	}, "buffers")
+}
+
+func TestDeref(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"value", Query{Args: []string{"bytes.Buffer"}}, []string{"Grow"}},
+		{"value with deref", Query{Args: []string{"bytes.Buffer"}, Deref: true}, []string{"Fill", "Grow"}},
+		{"pointer with deref", Query{Args: []string{"*bytes.Buffer"}, Deref: true}, []string{"Fill", "Grow"}},
+		{"unqualified with deref", Query{Args: []string{"Buffer"}, Deref: true, Unqualified: true}, []string{"Fill", "Grow", "Local"}},
+		// Only a single level of indirection is ignored.
+		{"double pointer", Query{Args: []string{"**bytes.Buffer"}, Deref: true}, nil},
+	}, "buffers")
+}
+
+func TestExcludeTypes(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"placeholder", Query{Args: []string{AnyType}}, []string{"Count", "Fill", "Grow", "Local"}},
+		{"excluded placeholder", Query{Args: []string{AnyType}, ExcludeTypes: []string{"int"}}, []string{"Fill", "Grow", "Local"}},
+		{"assignable", Query{Args: []string{"io.Reader"}, Assignable: true}, []string{"Fill"}},
+		{"excluded assignable", Query{Args: []string{"io.Reader"}, Assignable: true, ExcludeTypes: []string{"*bytes.Buffer"}}, nil},
+		{"excluded exact", Query{Args: []string{"int"}, ExcludeTypes: []string{"int"}}, nil},
+	}, "buffers")
+}
+
+func TestIgnoreCase(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"exact", Query{Args: []string{"[]BYTE"}}, nil},
+		{"folded", Query{Args: []string{"[]BYTE"}, IgnoreCase: true}, []string{"Write"}},
+		{"folded package", Query{Args: []string{"IO.reader"}, IgnoreCase: true}, []string{"Wrap"}},
+		{"folded unqualified", Query{Args: []string{"reader"}, IgnoreCase: true, Unqualified: true}, []string{"Local", "Wrap"}},
+		{"folded regex", Query{Args: []string{`IO\..*`}, IgnoreCase: true, Regex: true}, []string{"Wrap"}},
+		{"different type", Query{Args: []string{"BYTE"}, IgnoreCase: true}, nil},
+	}, "cases")
+}
+
+func TestParamNames(t *testing.T) {
+	testMatches(t, []matchTest{
+		{"arg name", Query{ArgName: "name"}, []string{"Open"}},
+		{"arg glob", Query{ArgName: "*Name"}, []string{"Rename"}},
+		{"ret name", Query{RetName: "err"}, []string{"Close", "Open"}},
+		{"both", Query{ArgName: "fd", RetName: "err"}, []string{"Close"}},
+		{"with types", Query{ArgName: "*ame", Args: []string{"string"}, And: true}, []string{"Open", "Rename"}},
+		{"blank", Query{ArgName: "_"}, nil},
+		{"any name", Query{ArgName: "*"}, []string{"Close", "Open", "Rename"}},
+	}, "names")
+
+	s := loadTest(t, "names")
+	if _, err := s.Match(Query{ArgName: "["}); err == nil {
+		t.Error("got no error for a malformed glob")
+	}
+}
+
+func TestInterfaceMethods(t *testing.T) {
+	s := loadTest(t, "ifaces")
+	matches, err := s.Match(Query{Rets: []string{"error"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, m := range matches {
+		got[m.Func.Name()] = m.InterfaceMethod()
+	}
+	want := map[string]bool{"Open": false, "Put": false, "Get": true, "Flush": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(matches) != len(want) {
+		t.Errorf("got %v, want embedded methods only once", matchNames(matches))
+	}
+}
@@ -0,0 +1,75 @@
+package uses
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// modCacheDir returns the location of the module cache, in the first
+// entry of ctx.Build's GOPATH unless GOMODCACHE is set, or the empty
+// string if it can't be determined.
+func (ctx *Context) modCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(ctx.Build.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// modCacheImportPath reports whether dir, a relative or absolute
+// directory, is a package directory inside the module cache and, if
+// so, returns its import path. The version suffixes (@v1.2.3) are
+// stripped and the cache's case encoding (!a for A) is reversed.
+// Import paths are never mapped, even if they happen to name a
+// directory relative to the working directory.
+func (ctx *Context) modCacheImportPath(dir string) (string, bool) {
+	if !build.IsLocalImport(dir) && !filepath.IsAbs(dir) {
+		return "", false
+	}
+	cache := ctx.modCacheDir()
+	if cache == "" {
+		return "", false
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(cache, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if parts[0] == "cache" {
+		return "", false
+	}
+	for i, part := range parts {
+		if index := strings.Index(part, "@"); index != -1 {
+			parts[i] = part[:index]
+		}
+	}
+
+	return unescapeModPath(strings.Join(parts, "/")), true
+}
+
+func unescapeModPath(path string) string {
+	var buf []rune
+	bang := false
+	for _, r := range path {
+		if bang {
+			r = unicode.ToUpper(r)
+			bang = false
+		} else if r == '!' {
+			bang = true
+			continue
+		}
+		buf = append(buf, r)
+	}
+	return string(buf)
+}
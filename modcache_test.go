@@ -0,0 +1,59 @@
+package uses
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModCacheImportPath(t *testing.T) {
+	ctx := newTestContext(t)
+	dir := filepath.Join(ctx.Build.GOPATH, "pkg", "mod", "example.com", "!foo@v1.0.0", "bar")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{dir, "example.com/Foo/bar", true},
+		{"./" + filepath.ToSlash(rel), "example.com/Foo/bar", true},
+		{filepath.Join(ctx.Build.GOPATH, "pkg", "mod", "cache", "download"), "", false},
+		{filepath.Join(ctx.Build.GOPATH, "src", "platform", "a"), "", false},
+		{"example.com/Foo/bar", "", false},
+		// An import path is not a directory, even if it happens to
+		// name one relative to the working directory.
+		{filepath.ToSlash(rel), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ctx.modCacheImportPath(tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("modCacheImportPath(%q) = %q, %t, want %q, %t", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoadModCacheDir(t *testing.T) {
+	ctx := newTestContext(t)
+	dir := filepath.Join(ctx.Build.GOPATH, "pkg", "mod", "example.com", "!foo@v1.0.0", "bar")
+	s := Load(ctx, []string{dir})
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	if len(s.Loaded) != 1 || s.Loaded[0] != "example.com/Foo/bar" {
+		t.Errorf("loaded %v, want [example.com/Foo/bar]", s.Loaded)
+	}
+	matches, err := s.Match(Query{Rets: []string{"*example.com/Foo/bar.Bar"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := matchNames(matches); len(names) != 1 || names[0] != "New" {
+		t.Errorf("got %v, want [New]", names)
+	}
+}
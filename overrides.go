@@ -0,0 +1,62 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"fmt"
+)
+
+// Override is a method declared on a type that shadows a method
+// promoted from one of the type's embedded fields.
+type Override struct {
+	Type   *types.TypeName
+	Method *types.Func
+	// Shadowed is the promoted method that Method hides.
+	Shadowed *types.Func
+
+	keepVendor bool
+}
+
+func (o Override) String() string {
+	origin := o.Shadowed.Name()
+	if recv := o.Shadowed.Type().(*types.Signature).Recv(); recv != nil {
+		origin = typeString(recv.Type(), o.keepVendor) + "." + origin
+	}
+	return fmt.Sprintf("%s.%s shadows %s", o.Type.Name(), o.Method.Name(), origin)
+}
+
+// Overrides finds methods that shadow methods promoted from embedded
+// fields.
+func (s *Snapshot) Overrides() map[string][]Override {
+	overrides := make(map[string][]Override)
+	for _, named := range s.named {
+		tn := named.Obj()
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+
+		for i := 0; i < named.NumMethods(); i++ {
+			method := named.Method(i)
+			for j := 0; j < st.NumFields(); j++ {
+				field := st.Field(j)
+				if !field.Anonymous() {
+					continue
+				}
+				typ := field.Type()
+				if _, ok := typ.Underlying().(*types.Interface); !ok {
+					if _, ok := typ.(*types.Pointer); !ok {
+						typ = types.NewPointer(typ)
+					}
+				}
+				sel := types.NewMethodSet(typ).Lookup(method.Pkg(), method.Name())
+				if sel == nil {
+					continue
+				}
+				path := tn.Pkg().Path()
+				overrides[path] = append(overrides[path], Override{tn, method, sel.Obj().(*types.Func), s.keepVendor})
+			}
+		}
+	}
+	return overrides
+}
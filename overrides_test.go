@@ -0,0 +1,21 @@
+package uses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	s := loadTest(t, "overrides")
+	var got []string
+	for _, o := range s.Overrides()["overrides"] {
+		got = append(got, o.String())
+	}
+	want := []string{
+		"Conn.Close shadows overrides.base.Close",
+		"Conn.Read shadows io.Reader.Read",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package uses
+
+import (
+	"github.com/kisielk/gotool"
+
+	"go/build"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// matchPattern returns a function reporting whether an import path
+// matches pattern, where ... matches any string, like in the go
+// tool. As special cases, x/... also matches x itself, and .../x
+// also matches x.
+func matchPattern(pattern string) func(string) bool {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
+	if strings.HasSuffix(re, `/.*`) {
+		re = re[:len(re)-len(`/.*`)] + `(/.*)?`
+	}
+	if strings.HasPrefix(re, `.*/`) {
+		re = `(.*/)?` + re[len(`.*/`):]
+	}
+	reg := regexp.MustCompile(`^` + re + `$`)
+	return reg.MatchString
+}
+
+// ResolvePackages expands the entries of -pkgs into import paths.
+// Entries are evaluated in order: plain entries add the packages
+// they expand to, entries prefixed with ! remove all packages
+// selected so far that match them. Patterns containing ... are also
+// expanded across the modules of the go.work workspace, if any, whose
+// packages are always referred to by their import paths.
+func (ctx *Context) ResolvePackages(entries []string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, "!") {
+			match := matchPattern(entry[1:])
+			var kept []string
+			for _, path := range paths {
+				if match(path) {
+					delete(seen, path)
+				} else {
+					kept = append(kept, path)
+				}
+			}
+			paths = kept
+			continue
+		}
+
+		expanded := ctx.expandPattern(entry)
+		if strings.Contains(entry, "...") && ctx.workspace != nil {
+			if entry == "./..." {
+				// In a workspace, ./... at its root covers all of
+				// its modules.
+				entry = "..."
+			}
+			expanded = append(expanded, ctx.workspace.expand(entry)...)
+		}
+		for _, path := range expanded {
+			if build.IsLocalImport(path) {
+				// Refer to the packages of the workspace by their
+				// import paths, so that both expansions of
+				// patterns agree on them.
+				if importPath, ok := ctx.workspace.importPath(path); ok {
+					path = importPath
+				}
+			}
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// expandPattern expands an import path pattern containing ... into
+// the packages it matches, leaving other paths as they are. Like the
+// go tool, it excludes packages in vendor and testdata directories
+// unless the pattern names them explicitly. Patterns are expanded
+// in ctx.Build's GOPATH.
+func (ctx *Context) expandPattern(pattern string) []string {
+	if !strings.Contains(pattern, "...") {
+		return []string{pattern}
+	}
+	gctx := gotool.Context{BuildContext: ctx.Build}
+	var paths []string
+	for _, path := range gctx.ImportPaths([]string{pattern}) {
+		if excludedDir(path) && !excludedDir(pattern) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func excludedDir(path string) bool {
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		if elem == "vendor" || elem == "testdata" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package uses
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"blank/app", "blank/app", true},
+		{"blank/app", "blank/apps", false},
+		{"blank/...", "blank", true},
+		{"blank/...", "blank/app", true},
+		{"blank/...", "blanket", false},
+		{".../util", "util", true},
+		{".../util", "blank/util", true},
+		{".../util", "blank/futil", false},
+		{"blank/.../x", "blank/a/b/x", true},
+		{"blank/.../x", "blank/a/b/y", false},
+		{"blank.app", "blankxapp", false},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern)(tt.path); got != tt.want {
+			t.Errorf("matchPattern(%q)(%q) = %t, want %t", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePackages(t *testing.T) {
+	ctx := newTestContext(t)
+	tests := []struct {
+		entries []string
+		want    []string
+	}{
+		{[]string{"blank/..."}, []string{"blank/app", "blank/driver", "blank/other", "blank/util"}},
+		{[]string{"blank/...", "!blank/util"}, []string{"blank/app", "blank/driver", "blank/other"}},
+		{[]string{"blank/...", "!.../util", "!blank/o..."}, []string{"blank/app", "blank/driver"}},
+		// Entries apply in order: later inclusions add back what
+		// earlier exclusions removed, and exclusions only remove
+		// packages selected before them.
+		{[]string{"blank/...", "!blank/...", "blank/util"}, []string{"blank/util"}},
+		{[]string{"!blank/app", "blank/app"}, []string{"blank/app"}},
+		{[]string{"blank/util", "blank/...", "!blank/app"}, []string{"blank/util", "blank/driver", "blank/other"}},
+	}
+	for _, tt := range tests {
+		if got := ctx.ResolvePackages(tt.entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResolvePackages(%q) = %q, want %q", tt.entries, got, tt.want)
+		}
+	}
+}
+
+func TestExcludePackages(t *testing.T) {
+	ctx := newTestContext(t)
+	ctx.ExcludePackages = []string{".../other"}
+	s := Load(ctx, ctx.ResolvePackages([]string{"blank/...", "!blank/util"}))
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	if want := []string{"blank/app", "blank/driver"}; !reflect.DeepEqual(s.Loaded, want) {
+		t.Errorf("got %q, want %q", s.Loaded, want)
+	}
+}
+
+func TestExpandPattern(t *testing.T) {
+	ctx := newTestContext(t)
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"expand/a", []string{"expand/a"}},
+		// Directories without Go files, such as expand/docs, are
+		// skipped, and so are vendor and testdata directories.
+		{"expand/...", []string{"expand/a", "expand/a/b"}},
+		{"expand/a/...", []string{"expand/a", "expand/a/b"}},
+		// Unless the pattern names them.
+		{"expand/vendor/...", []string{"expand/vendor/dep"}},
+		{"expand/testdata/...", []string{"expand/testdata/fixture"}},
+	}
+	for _, tt := range tests {
+		if got := ctx.expandPattern(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandPattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+
+	defer chdir(t, filepath.Join(ctx.Build.GOPATH, "src", "expand"))()
+	if got, want := ctx.expandPattern("./..."), []string{"./a", "./a/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expandPattern(./...) = %q, want %q", got, want)
+	}
+	s := Load(ctx, ctx.ResolvePackages([]string{"./..."}))
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	matches, err := s.Match(Query{Rets: []string{"error"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := matchNames(matches), []string{"A", "B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("./...: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,224 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"fmt"
+	"strings"
+)
+
+// Expr is a boolean expression over the parameters and results of a
+// function, as parsed by ParseExpr.
+type Expr interface {
+	eval(q Query, sig *types.Signature) bool
+	// types appends the types the expression refers to.
+	types(typs []string) []string
+	// mapTypes returns a copy of the expression with each type
+	// replaced by f's result for it.
+	mapTypes(f func(string) string) Expr
+	// terms appends the types of the expression's arg: and ret:
+	// terms to args and rets respectively.
+	terms(args, rets []string) ([]string, []string)
+}
+
+type andExpr struct{ x, y Expr }
+type orExpr struct{ x, y Expr }
+type notExpr struct{ x Expr }
+
+// predExpr matches functions with a parameter, or a result if ret is
+// set, of type typ.
+type predExpr struct {
+	ret bool
+	typ string
+}
+
+func (e andExpr) eval(q Query, sig *types.Signature) bool {
+	return e.x.eval(q, sig) && e.y.eval(q, sig)
+}
+
+func (e orExpr) eval(q Query, sig *types.Signature) bool {
+	return e.x.eval(q, sig) || e.y.eval(q, sig)
+}
+
+func (e notExpr) eval(q Query, sig *types.Signature) bool {
+	return !e.x.eval(q, sig)
+}
+
+func (e predExpr) eval(q Query, sig *types.Signature) bool {
+	if e.ret {
+		return q.tupleHasType(sig.Results(), e.typ, false)
+	}
+	return q.tupleHasType(sig.Params(), e.typ, sig.Variadic())
+}
+
+func (e andExpr) types(typs []string) []string  { return e.y.types(e.x.types(typs)) }
+func (e orExpr) types(typs []string) []string   { return e.y.types(e.x.types(typs)) }
+func (e notExpr) types(typs []string) []string  { return e.x.types(typs) }
+func (e predExpr) types(typs []string) []string { return append(typs, e.typ) }
+
+func (e andExpr) mapTypes(f func(string) string) Expr {
+	return andExpr{e.x.mapTypes(f), e.y.mapTypes(f)}
+}
+
+func (e orExpr) mapTypes(f func(string) string) Expr {
+	return orExpr{e.x.mapTypes(f), e.y.mapTypes(f)}
+}
+
+func (e notExpr) mapTypes(f func(string) string) Expr  { return notExpr{e.x.mapTypes(f)} }
+func (e predExpr) mapTypes(f func(string) string) Expr { return predExpr{e.ret, f(e.typ)} }
+
+func (e andExpr) terms(args, rets []string) ([]string, []string) {
+	args, rets = e.x.terms(args, rets)
+	return e.y.terms(args, rets)
+}
+
+func (e orExpr) terms(args, rets []string) ([]string, []string) {
+	args, rets = e.x.terms(args, rets)
+	return e.y.terms(args, rets)
+}
+
+func (e notExpr) terms(args, rets []string) ([]string, []string) { return e.x.terms(args, rets) }
+
+func (e predExpr) terms(args, rets []string) ([]string, []string) {
+	if e.ret {
+		return args, append(rets, e.typ)
+	}
+	return append(args, e.typ), rets
+}
+
+// ParseExpr parses a query expression such as
+//
+//	(arg:io.Reader | arg:io.Writer) & ret:error
+//
+// arg:T and ret:T match functions with a parameter or result of type
+// T respectively. Expressions can be combined with & (and), | (or)
+// and ! (not), in decreasing order of precedence ! & |, and grouped
+// with parentheses.
+func ParseExpr(s string) (Expr, error) {
+	p := &exprParser{s: s}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos])
+	}
+	return e, nil
+}
+
+type exprParser struct {
+	s   string
+	pos int
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Syntax error in query at column %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// consume skips c if it is the next non-space character.
+func (p *exprParser) consume(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (Expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume('|') {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = orExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseAnd() (Expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume('&') {
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = andExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseUnary() (Expr, error) {
+	switch {
+	case p.consume('!'):
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{x}, nil
+	case p.consume('('):
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, p.errorf("missing )")
+		}
+		return x, nil
+	}
+	return p.parsePred()
+}
+
+func (p *exprParser) parsePred() (Expr, error) {
+	p.skipSpace()
+	var e predExpr
+	switch rest := p.s[p.pos:]; {
+	case strings.HasPrefix(rest, "arg:"):
+	case strings.HasPrefix(rest, "ret:"):
+		e.ret = true
+	default:
+		return nil, p.errorf("expected arg: or ret:")
+	}
+	p.pos += len("arg:")
+
+	// The type extends up to the next operator or unbalanced closing
+	// parenthesis; types such as func(int, string) error contain
+	// parentheses and spaces of their own.
+	start := p.pos
+	depth := 0
+loop:
+	for ; p.pos < len(p.s); p.pos++ {
+		switch p.s[p.pos] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case '&', '|':
+			if depth == 0 {
+				break loop
+			}
+		}
+	}
+	e.typ = strings.TrimSpace(p.s[start:p.pos])
+	if e.typ == "" {
+		return nil, p.errorf("missing type")
+	}
+	return e, nil
+}
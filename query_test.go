@@ -0,0 +1,90 @@
+package uses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	arg := func(typ string) Expr { return predExpr{false, typ} }
+	ret := func(typ string) Expr { return predExpr{true, typ} }
+	tests := []struct {
+		in   string
+		want Expr
+	}{
+		{"arg:io.Reader", arg("io.Reader")},
+		{"  ret:error  ", ret("error")},
+		{"arg:a | arg:b & ret:c", orExpr{arg("a"), andExpr{arg("b"), ret("c")}}},
+		{"arg:a | arg:b | arg:c", orExpr{orExpr{arg("a"), arg("b")}, arg("c")}},
+		{"!arg:a & ret:b", andExpr{notExpr{arg("a")}, ret("b")}},
+		{"!!arg:a", notExpr{notExpr{arg("a")}}},
+		{"(arg:a | arg:b) & ret:c", andExpr{orExpr{arg("a"), arg("b")}, ret("c")}},
+		{"!(arg:a)", notExpr{arg("a")}},
+		// Types keep their own parentheses, brackets and spaces.
+		{"ret:func(int, string) error & arg:io.Reader", andExpr{ret("func(int, string) error"), arg("io.Reader")}},
+		{"(arg:map[string]struct{ X int })", arg("map[string]struct{ X int }")},
+	}
+	for _, tt := range tests {
+		got, err := ParseExpr(tt.in)
+		if err != nil {
+			t.Errorf("%q: %s", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "Syntax error in query at column 1: expected arg: or ret:"},
+		{"par:a", "Syntax error in query at column 1: expected arg: or ret:"},
+		{"arg:", "Syntax error in query at column 5: missing type"},
+		{"arg:a &", "Syntax error in query at column 8: expected arg: or ret:"},
+		{"arg: | ret:b", "Syntax error in query at column 6: missing type"},
+		{"(arg:a", "Syntax error in query at column 7: missing )"},
+		{"arg:a)", "Syntax error in query at column 6: unexpected ')'"},
+	}
+	for _, tt := range tests {
+		_, err := ParseExpr(tt.in)
+		if err == nil {
+			t.Errorf("%q: got no error", tt.in)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestExprTypes(t *testing.T) {
+	expr, err := ParseExpr("(arg:io.Reader | !arg:io.Writer) & ret:error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := expr.types(nil), []string{"io.Reader", "io.Writer", "error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got types %q, want %q", got, want)
+	}
+	mapped := expr.mapTypes(func(typ string) string { return "*" + typ })
+	if got, want := mapped.types(nil), []string{"*io.Reader", "*io.Writer", "*error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got mapped types %q, want %q", got, want)
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	expr, err := ParseExpr("(arg:io.Reader | !ret:int) & ret:error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, rets := Query{Args: []string{"string"}, Expr: expr}.Terms()
+	if want := []string{"string", "io.Reader"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got args %q, want %q", args, want)
+	}
+	if want := []string{"int", "error"}; !reflect.DeepEqual(rets, want) {
+		t.Errorf("got rets %q, want %q", rets, want)
+	}
+}
@@ -0,0 +1,151 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"go/ast"
+)
+
+// reachableFromExported returns the objects of all source packages
+// that can be reached from their package's exported API: exported
+// package-level objects, init functions and main. An object is
+// reachable if a reachable declaration refers to it. All methods of a
+// reachable type, including those declared by interfaces, are
+// considered reachable, as they may be called through interfaces or
+// reflection.
+//
+// Unexported objects can't be referred to by other packages, so each
+// package's graph is built independently.
+func (s *Snapshot) reachableFromExported() map[types.Object]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reachable != nil {
+		return s.reachable
+	}
+
+	s.reachable = make(map[types.Object]bool)
+	for _, src := range s.sources {
+		refs, roots := src.references()
+		queue := roots
+		for len(queue) > 0 {
+			obj := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			if s.reachable[obj] {
+				continue
+			}
+			s.reachable[obj] = true
+			queue = append(queue, refs[obj]...)
+		}
+	}
+	return s.reachable
+}
+
+// references builds the reference graph of the package's top-level
+// declarations and returns it, along with the graph's roots.
+func (src *sourcePackage) references() (refs map[types.Object][]types.Object, roots []types.Object) {
+	refs = make(map[types.Object][]types.Object)
+	// uses returns the objects of the package referred to in node.
+	uses := func(node ast.Node) []types.Object {
+		var objs []types.Object
+		if node == nil {
+			return nil
+		}
+		ast.Inspect(node, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Ident); ok {
+				if obj := src.info.Uses[ident]; obj != nil && isTopLevel(obj) {
+					objs = append(objs, obj)
+				}
+			}
+			return true
+		})
+		return objs
+	}
+	addRoot := func(obj types.Object) {
+		if obj == nil {
+			return
+		}
+		if (obj.Exported() && isTopLevel(obj) && !isMethod(obj)) || obj.Name() == "_" {
+			roots = append(roots, obj)
+		}
+	}
+
+	for _, file := range src.files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				obj := src.info.Defs[decl.Name]
+				if obj == nil {
+					continue
+				}
+				refs[obj] = append(refs[obj], uses(decl)...)
+				if decl.Recv != nil && len(decl.Recv.List) == 1 {
+					// Reaching a type reaches its methods.
+					if recv := src.recvTypeName(decl.Recv.List[0].Type); recv != nil {
+						refs[recv] = append(refs[recv], obj)
+					}
+				} else if decl.Name.Name == "init" || (decl.Name.Name == "main" && obj.Pkg().Name() == "main") {
+					roots = append(roots, obj)
+				}
+				addRoot(obj)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						objs := uses(spec)
+						for _, name := range spec.Names {
+							obj := src.info.Defs[name]
+							if obj == nil {
+								continue
+							}
+							refs[obj] = append(refs[obj], objs...)
+							addRoot(obj)
+						}
+					case *ast.TypeSpec:
+						obj := src.info.Defs[spec.Name]
+						if obj == nil {
+							continue
+						}
+						refs[obj] = append(refs[obj], uses(spec.Type)...)
+						if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+							for i := 0; i < iface.NumExplicitMethods(); i++ {
+								refs[obj] = append(refs[obj], iface.ExplicitMethod(i))
+							}
+						}
+						addRoot(obj)
+					}
+				}
+			}
+		}
+	}
+	return refs, roots
+}
+
+// recvTypeName returns the type name of a method's receiver
+// expression, which is either T or *T.
+func (src *sourcePackage) recvTypeName(expr ast.Expr) types.Object {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if paren, ok := expr.(*ast.ParenExpr); ok {
+		return src.recvTypeName(paren.X)
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	return src.info.Uses[ident]
+}
+
+func isMethod(obj types.Object) bool {
+	fn, ok := obj.(*types.Func)
+	return ok && fn.Type().(*types.Signature).Recv() != nil
+}
+
+// isTopLevel reports whether obj is declared at package level or is
+// a method.
+func isTopLevel(obj types.Object) bool {
+	if obj.Pkg() == nil {
+		return false
+	}
+	return obj.Parent() == obj.Pkg().Scope() || isMethod(obj)
+}
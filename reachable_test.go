@@ -0,0 +1,22 @@
+package uses
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestReachableFromExported(t *testing.T) {
+	s := loadTest(t, "reach")
+	matches, err := s.Match(Query{ReachableFromExported: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := matchNames(matches)
+	sort.Strings(got)
+	// Hook is a function variable, and init functions are never matched.
+	want := []string{"Exported", "Hook", "close", "deeper", "fromBlank", "fromInit", "helper", "hooked"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func (s *Snapshot) lookupTypes(names []string) ([]types.Type, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var typs []types.Type
+	for _, name := range names {
+		typ, err := s.lookupType(name)
+		if err != nil {
+			return nil, err
+		}
+		typs = append(typs, typ)
+	}
+	return typs, nil
+}
+
+func (s *Snapshot) lookupInterface(name string) (*types.Interface, error) {
+	if name == "" {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	typ, err := s.lookupType(name)
+	if err != nil {
+		return nil, err
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", name)
+	}
+	return iface, nil
+}
+
+// lookupType resolves a query type such as io.Reader,
+// map[string][]*github.com/foo/bar.Thing or func(int) error,
+// importing packages as necessary. Predeclared types such as error
+// don't need to be qualified. Types declared by the snapshot's
+// packages, including unexported ones, resolve to the types being
+// matched. s.mu must be held.
+func (s *Snapshot) lookupType(name string) (types.Type, error) {
+	name = strings.TrimSpace(name)
+	if typ, ok := s.resolved[name]; ok {
+		s.ctx.Log.Debugf("Resolved %s from cache", name)
+		return typ, nil
+	}
+	typ, err := s.resolveType(name)
+	if err != nil {
+		return nil, err
+	}
+	s.resolved[name] = typ
+	return typ, nil
+}
+
+func (s *Snapshot) resolveType(name string) (types.Type, error) {
+	switch {
+	case strings.HasPrefix(name, "*"):
+		elem, err := s.lookupType(name[1:])
+		if err != nil {
+			return nil, err
+		}
+		return types.NewPointer(elem), nil
+	case strings.HasPrefix(name, "[]"):
+		elem, err := s.lookupType(name[2:])
+		if err != nil {
+			return nil, err
+		}
+		return types.NewSlice(elem), nil
+	case strings.HasPrefix(name, "["):
+		index := strings.Index(name, "]")
+		if index == -1 {
+			return nil, fmt.Errorf("Invalid array type %s", name)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(name[1:index]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid array length in %s", name)
+		}
+		elem, err := s.lookupType(name[index+1:])
+		if err != nil {
+			return nil, err
+		}
+		return types.NewArray(elem, n), nil
+	case strings.HasPrefix(name, "map["):
+		key, elem, ok := parseMap(name)
+		if !ok {
+			return nil, fmt.Errorf("Invalid map type %s", name)
+		}
+		keyType, err := s.lookupType(key)
+		if err != nil {
+			return nil, err
+		}
+		elemType, err := s.lookupType(elem)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewMap(keyType, elemType), nil
+	case literalType.MatchString(name):
+		return s.checkType(name)
+	}
+	if dir, elem, ok := parseChan(name); ok {
+		elemType, err := s.lookupType(elem)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewChan(dir, elemType), nil
+	}
+
+	index := strings.LastIndex(name, ".")
+	if index == -1 {
+		typ, ok := types.Universe.Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("%s is neither predeclared nor a package-qualified type", name)
+		}
+		return typ.Type(), nil
+	}
+	path, typName := name[:index], name[index+1:]
+
+	pkg, err := s.importQueried(nil, path)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't import %s: %s", path, err)
+	}
+	typ, ok := pkg.Scope().Lookup(typName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a type in %s", typName, path)
+	}
+
+	return typ.Type(), nil
+}
+
+var literalType = regexp.MustCompile(`^(func\s*\(|interface\s*{|struct\s*{)`)
+
+// checkType resolves a function, interface or struct type by
+// type-checking a declaration of it. The packages it refers to are
+// imported under aliases, as import paths aren't identifiers.
+func (s *Snapshot) checkType(name string) (types.Type, error) {
+	aliases := make(map[string]string)
+	var imports []string
+	src := qualifiedIdent.ReplaceAllStringFunc(name, func(ident string) string {
+		index := strings.LastIndex(ident, ".")
+		path := ident[:index]
+		alias, ok := aliases[path]
+		if !ok {
+			alias = fmt.Sprintf("p%d", len(aliases))
+			aliases[path] = alias
+			imports = append(imports, fmt.Sprintf("import %s %q\n", alias, path))
+		}
+		return alias + ident[index:]
+	})
+	src = "package p\n" + strings.Join(imports, "") + "type T " + src + "\n"
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid type %s", name)
+	}
+	var conf types.Config
+	conf.Import = s.importQueried
+	pkg, err := conf.Check("", fset, []*ast.File{f}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid type %s: %s", name, err)
+	}
+	return pkg.Scope().Lookup("T").Type().Underlying(), nil
+}
+
+// importQueried imports path for resolving query types, preferring
+// the snapshot's packages so that types resolve to those being
+// matched. s.mu must be held.
+func (s *Snapshot) importQueried(imports map[string]*types.Package, path string) (*types.Package, error) {
+	if pkg, ok := s.pkgs[path]; ok {
+		return pkg, nil
+	}
+	return s.ctx.importPackage(s.ctx.allImports, path)
+}
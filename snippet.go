@@ -0,0 +1,99 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Snippet returns a minimal example of calling the matched function:
+// the import of its package, a declaration of the receiver for
+// methods, and a call with a placeholder comment for each argument,
+// such as
+//
+//	import "bytes"
+//
+//	r := bytes.NewReader(/* []byte */)
+func Snippet(m Match) string {
+	rec := m.Record()
+	opts := FormatOptions{Qualification: QualifyName}
+	names := make(map[string]bool)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "import %s\n\n", strconv.Quote(strings.TrimSuffix(rec.Package, "_test")))
+
+	callee := m.Func.Pkg.Name() + "." + rec.Name
+	if recv := rec.Receiver; recv != nil {
+		name := recv.Name
+		if name == "" || name == "_" {
+			name = placeholderName(recv.typ)
+		}
+		names[name] = true
+		fmt.Fprintf(&b, "var %s %s\n", name, opts.qualify(recv.Type))
+		callee = name + "." + rec.Name
+	}
+
+	args := make([]string, len(rec.Params))
+	for i, param := range rec.Params {
+		typ := opts.qualify(param.Type)
+		if rec.Variadic && i == len(rec.Params)-1 {
+			typ = "..." + opts.qualify(strings.TrimPrefix(param.Type, "[]"))
+		}
+		args[i] = "/* " + typ + " */"
+	}
+	call := fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", "))
+
+	if len(rec.Results) == 0 {
+		b.WriteString(call)
+		return b.String()
+	}
+	lhs := make([]string, len(rec.Results))
+	for i, res := range rec.Results {
+		name := res.Name
+		if name == "" || name == "_" {
+			name = placeholderName(res.typ)
+		}
+		lhs[i] = uniqueName(name, names)
+	}
+	fmt.Fprintf(&b, "%s := %s", strings.Join(lhs, ", "), call)
+	return b.String()
+}
+
+// placeholderName derives a variable name from typ, such as err for
+// error or r for *bytes.Reader.
+func placeholderName(typ types.Type) string {
+	if isError(typ) {
+		return "err"
+	}
+	typ = deref(typ)
+	var name string
+	switch typ := typ.(type) {
+	case *types.Named:
+		name = typ.Obj().Name()
+	case *types.Basic:
+		name = typ.Name()
+	case *types.Slice:
+		if basic, ok := typ.Elem().(*types.Basic); ok {
+			name = basic.Name()
+		}
+	}
+	for _, r := range name {
+		return string(unicode.ToLower(r))
+	}
+	return "v"
+}
+
+// uniqueName returns name, or name followed by a number if it is
+// already taken, and marks the result as taken.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	taken[unique] = true
+	return unique
+}
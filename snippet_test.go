@@ -0,0 +1,33 @@
+package uses
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	s := loadTest(t, "snippets")
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"NewReader", "import \"snippets\"\n\nr := snippets.NewReader(/* []byte */)"},
+		{"Read", "import \"snippets\"\n\nvar r *snippets.Reader\nn, err := r.Read(/* []byte */)"},
+		// Unnamed receivers and results get names derived from their
+		// types, which are made unique.
+		{"Clone", "import \"snippets\"\n\nvar r *snippets.Reader\nr2 := r.Clone()"},
+		{"Pair", "import \"snippets\"\n\ni, i2 := snippets.Pair()"},
+		{"Join", "import \"snippets\"\n\ns := snippets.Join(/* string */, /* ...string */)"},
+		{"Copy", "import \"snippets\"\n\nerr := snippets.Copy(/* *bytes.Buffer */)"},
+		{"Reset", "import \"snippets\"\n\nsnippets.Reset()"},
+	}
+	for _, tt := range tests {
+		matches, err := s.Match(Query{Name: tt.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 {
+			t.Fatalf("%s: got %v", tt.name, matchNames(matches))
+		}
+		if got := Snippet(matches[0]); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
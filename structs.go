@@ -0,0 +1,116 @@
+package uses
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	fieldNames = regexp.MustCompile(`^([\pL_][\pL\pN_]*(?:\s*,\s*[\pL_][\pL\pN_]*)*)\s+(.*)$`)
+	fieldTag   = regexp.MustCompile("\\s+(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)$")
+)
+
+// stripFieldNames rewrites all struct types in the type string s so
+// that they only list their field types, without names or tags. Both
+// the output of types.Type.String and user-written types, which may
+// declare several fields at once as in struct{ X, Y int }, are
+// supported.
+func stripFieldNames(s string) string {
+	var out []string
+	for {
+		index := strings.Index(s, "struct{")
+		if index == -1 {
+			out = append(out, s)
+			break
+		}
+		out = append(out, s[:index+len("struct{")])
+		s = s[index+len("struct{"):]
+		end := closingBrace(s)
+		if end == -1 {
+			out = append(out, s)
+			break
+		}
+
+		var typs []string
+		for _, field := range splitTopLevel(s[:end], ';') {
+			field = strings.TrimSpace(fieldTag.ReplaceAllString(strings.TrimSpace(field), ""))
+			if field == "" {
+				continue
+			}
+			n := 1
+			if m := fieldNames.FindStringSubmatch(field); m != nil {
+				n = len(strings.Split(m[1], ","))
+				field = m[2]
+			}
+			field = stripFieldNames(field)
+			for i := 0; i < n; i++ {
+				typs = append(typs, field)
+			}
+		}
+		out = append(out, strings.Join(typs, "; "), "}")
+		s = s[end+1:]
+	}
+	return strings.Join(out, "")
+}
+
+// closingBrace returns the index of the } closing an already opened
+// {, skipping over nested brackets and quoted strings.
+func closingBrace(s string) int {
+	depth := 0
+	var quote rune
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+		case r == '{':
+			depth++
+		case r == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s at occurrences of sep that aren't nested in
+// brackets or quoted strings.
+func splitTopLevel(s string, sep rune) []string {
+	var parts []string
+	depth := 0
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+		case r == '{' || r == '[' || r == '(':
+			depth++
+		case r == '}' || r == ']' || r == ')':
+			depth--
+		case r == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,131 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// Suggestion is a parameter of a concrete type that is only used for
+// its methods, which an interface could provide.
+type Suggestion struct {
+	fnc     *types.Func
+	param   *types.Var
+	methods []*types.Func
+
+	keepVendor bool
+}
+
+func (s Suggestion) String() string {
+	methods := make([]string, len(s.methods))
+	for i, method := range s.methods {
+		sig := method.Type().(*types.Signature)
+		methods[i] = fmt.Sprintf("%s(%s) (%s)", method.Name(), argsToString(sig.Params(), s.keepVendor), argsToString(sig.Results(), s.keepVendor))
+	}
+
+	return fmt.Sprintf("%s: %s %s could be interface { %s }",
+		s.fnc.Name(), noDot(s.param.Name()), typeString(s.param.Type(), s.keepVendor), strings.Join(methods, "; "))
+}
+
+// SuggestInterfaces finds parameters of concrete types that are only
+// ever used to call methods on, and which could thus be replaced by
+// an interface consisting of those methods. If typs is non-empty,
+// only parameters of those types are considered. Only packages that
+// were type-checked from source can be analysed.
+func (s *Snapshot) SuggestInterfaces(typs []string) map[string][]Suggestion {
+	suggestions := make(map[string][]Suggestion)
+	for path, src := range s.sources {
+		for _, file := range src.files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Body == nil {
+					continue
+				}
+				obj, ok := src.info.Defs[fn.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				params := obj.Type().(*types.Signature).Params()
+				for i := 0; i < params.Len(); i++ {
+					param := params.At(i)
+					if _, ok := param.Type().Underlying().(*types.Interface); ok {
+						continue
+					}
+					if len(typs) > 0 && !containsString(typs, s.TypeString(param.Type())) {
+						continue
+					}
+					methods := methodsUsed(src.info, fn.Body, param)
+					if len(methods) == 0 {
+						continue
+					}
+					suggestions[path] = append(suggestions[path], Suggestion{obj, param, methods, s.keepVendor})
+				}
+			}
+		}
+	}
+
+	return suggestions
+}
+
+// methodsUsed returns the methods called on param in body, sorted by
+// name. It returns nil if param is used in any way other than
+// selecting a method on it.
+func methodsUsed(info *types.Info, body *ast.BlockStmt, param *types.Var) []*types.Func {
+	methods := make(map[string]*types.Func)
+	methodUses := make(map[*ast.Ident]bool)
+	ast.Inspect(body, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok || info.Uses[ident] != param {
+			return true
+		}
+		if selection, ok := info.Selections[sel]; ok && selection.Kind() == types.MethodVal {
+			method := selection.Obj().(*types.Func)
+			methods[method.Name()] = method
+			methodUses[ident] = true
+		}
+		return true
+	})
+
+	otherUse := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Ident)
+		if ok && info.Uses[ident] == param && !methodUses[ident] {
+			otherUse = true
+		}
+		return !otherUse
+	})
+	if otherUse {
+		return nil
+	}
+
+	var ret []*types.Func
+	for _, name := range sortedFuncKeys(methods) {
+		ret = append(ret, methods[name])
+	}
+	return ret
+}
+
+func sortedFuncKeys(m map[string]*types.Func) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
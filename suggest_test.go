@@ -0,0 +1,45 @@
+package uses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggestInterfaces(t *testing.T) {
+	ctx := newTestContext(t)
+	s := Load(ctx, []string{"suggest"})
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	tests := []struct {
+		typs []string
+		want []string
+	}{
+		{nil, []string{"Read: f *os.File could be interface { Read(b []byte) (n int, err error) }"}},
+		{[]string{"*os.File"}, []string{"Read: f *os.File could be interface { Read(b []byte) (n int, err error) }"}},
+		{[]string{"*bytes.Buffer"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, sug := range s.SuggestInterfaces(tt.typs)["suggest"] {
+			got = append(got, sug.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %q, want %q", tt.typs, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestInterfacesSnapshot(t *testing.T) {
+	ctx := newTestContext(t)
+	s := Load(ctx, []string{"suggest"})
+	want := s.SuggestInterfaces(nil)
+	// Later loads don't change what an earlier snapshot suggests.
+	Load(ctx, []string{"resolve"})
+	if got := s.SuggestInterfaces(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v after another load, want %v", got, want)
+	}
+	if got := Load(ctx, []string{"resolve"}).SuggestInterfaces(nil); len(got) != 0 {
+		t.Errorf("got %v for resolve, want no suggestions", got)
+	}
+}
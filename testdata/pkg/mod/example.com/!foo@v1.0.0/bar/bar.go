@@ -0,0 +1,5 @@
+package bar
+
+func New() *Bar { return &Bar{} }
+
+type Bar struct{}
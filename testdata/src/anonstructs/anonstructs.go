@@ -0,0 +1,14 @@
+package anonstructs
+
+func Point() struct{ X int } { return struct{ X int }{} }
+func Tagged() struct {
+	Y int "json:\"y\""
+} {
+	return struct {
+		Y int "json:\"y\""
+	}{}
+}
+func Pair() struct{ A, B string }        { return struct{ A, B string }{} }
+func Text() struct{ X string }           { return struct{ X string }{} }
+func Nested() map[string]struct{ N int } { return nil }
+func Take(v struct{ Z int })             {}
@@ -0,0 +1,19 @@
+package argkinds
+
+import "io"
+
+type Config struct{}
+
+type Store interface {
+	Get(key string) string
+}
+
+func Serve(s Store, c Config)               {}
+func Pipe(r io.Reader, w io.Writer)         {}
+func Copy(dst []byte, src io.Reader, n int) {}
+func Values(a, b int)                       {}
+func Print(v interface{})                   {}
+func Empty()                                {}
+
+// Load's receiver doesn't count as a parameter.
+func (Config) Load(s Store) {}
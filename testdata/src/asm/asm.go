@@ -0,0 +1,6 @@
+package asm
+
+// Add is implemented in assembly.
+func Add(x, y int) int
+
+func Sub(x, y int) int { return x - y }
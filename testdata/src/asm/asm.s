@@ -0,0 +1 @@
+// The implementation of Add would go here.
@@ -0,0 +1,8 @@
+package app
+
+import (
+	_ "blank/driver"
+	"blank/util"
+)
+
+func Run() { util.Help() }
@@ -0,0 +1,6 @@
+package other
+
+import (
+	_ "blank/driver"
+	_ "fmt"
+)
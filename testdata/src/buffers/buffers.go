@@ -0,0 +1,11 @@
+package buffers
+
+import "bytes"
+
+// Buffer has the same name as bytes.Buffer.
+type Buffer struct{}
+
+func Fill(b *bytes.Buffer) {}
+func Grow(b bytes.Buffer)  {}
+func Local(b *Buffer)      {}
+func Count(n int)          {}
@@ -0,0 +1,10 @@
+package cases
+
+import "io"
+
+type Reader struct{}
+
+func Write(p []byte)     {}
+func Wrap(r io.Reader)   {}
+func Local(r Reader)     {}
+func Split(s string) int { return 0 }
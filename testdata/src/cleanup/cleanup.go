@@ -0,0 +1,10 @@
+package cleanup
+
+type DB struct{}
+
+func Setup() (*DB, func(), error)      { return nil, nil, nil }
+func SetupErr() (*DB, func() error)    { return nil, nil }
+func OnlyCleanup() func()              { return nil }
+func CleanupAndError() (func(), error) { return nil, nil }
+func Callback() (*DB, func(int))       { return nil, nil }
+func Open() (*DB, error)               { return nil, nil }
@@ -0,0 +1,21 @@
+package coverage
+
+type Full struct{}
+
+func (*Full) Read([]byte) (int, error) { return 0, nil }
+func (*Full) Close() error             { return nil }
+
+type ReadOnly struct{}
+
+func (ReadOnly) Read([]byte) (int, error) { return 0, nil }
+
+type CloseOnly struct{}
+
+func (*CloseOnly) Close() error { return nil }
+
+// WrongRead has a Read method of the wrong signature.
+type WrongRead struct{}
+
+func (WrongRead) Read(string) {}
+
+type None struct{}
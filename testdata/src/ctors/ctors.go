@@ -0,0 +1,11 @@
+package ctors
+
+type Config struct{}
+
+type Client struct{}
+
+func New() *Client                     { return nil }
+func NewWithConfig(cfg Config) *Client { return nil }
+func Default() Client                  { return Client{} }
+func (*Client) Clone() *Client         { return nil }
+func Helper() int                      { return 0 }
@@ -0,0 +1,15 @@
+package ctxpos
+
+import "context"
+
+func First(ctx context.Context, n int)             {}
+func Only(ctx context.Context)                     {}
+func Late(n int, ctx context.Context)              {}
+func Third(a, b string, ctx context.Context) error { return nil }
+func Without(n int)                                {}
+
+type Client struct{}
+
+// Do's receiver doesn't count as its first parameter.
+func (Client) Do(name string, ctx context.Context)  {}
+func (Client) Get(ctx context.Context, name string) {}
@@ -0,0 +1,15 @@
+package deps
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+type T int
+
+func One(r io.Reader) error                               { return nil }
+func Two(r io.Reader, d time.Duration) error              { return nil }
+func Three(b *bytes.Buffer, r io.Reader, d time.Duration) {}
+func Local(x T) []T                                       { return nil }
+func None(n int) string                                   { return "" }
@@ -0,0 +1,14 @@
+package directives
+
+//go:noinline
+func Hot() {}
+
+// Cold has a doc comment as well as a directive.
+//
+//go:nosplit
+func Cold() {}
+
+// Prose mentions go:noinline without being annotated.
+func Prose() {}
+
+func Bare() {}
@@ -0,0 +1,10 @@
+package dupargs
+
+func Move(x, y int)                             {}
+func Copy(dst, src []byte) int                  { return 0 }
+func Rename(from string, to string, force bool) {}
+func Mixed(n int, s string, m int)              {}
+
+// Neither Distinct nor Single take two parameters of the same type.
+func Distinct(n int, s string) {}
+func Single(n int)             {}
@@ -0,0 +1,18 @@
+package errs
+
+// Error is a custom error interface.
+type Error interface {
+	error
+	Code() int
+}
+
+type CodeError struct{}
+
+func (*CodeError) Error() string { return "" }
+func (*CodeError) Code() int     { return 0 }
+
+func Coded() (int, *CodeError)     { return 0, nil }
+func Iface() Error                 { return nil }
+func Plain() error                 { return nil }
+func NotLast() (*CodeError, int)   { return nil, 0 }
+func Value() (CodeError, int, int) { return CodeError{}, 0, 0 }
@@ -0,0 +1,7 @@
+package format
+
+import "bytes"
+
+type T struct{}
+
+func (t *T) Write(b *bytes.Buffer, args ...string) (n int, err error) { return 0, nil }
@@ -0,0 +1,6 @@
+package inner
+
+type Inner struct{}
+
+// Close closes the Inner.
+func (Inner) Close() error { return nil }
@@ -0,0 +1,15 @@
+package outer
+
+import (
+	"sync"
+
+	"example.org/promoted/inner"
+)
+
+type Outer struct {
+	inner.Inner
+	sync.Mutex
+}
+
+// Open opens an Outer.
+func (*Outer) Open() error { return nil }
@@ -0,0 +1,3 @@
+package fixture
+
+func F() error { return nil }
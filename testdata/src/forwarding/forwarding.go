@@ -0,0 +1,19 @@
+package forwarding
+
+func pair() (int, error)         { return 0, nil }
+func use(n int, err error) error { return err }
+func one() int                   { return 0 }
+func check(n int)                {}
+
+func Return() error { return use(pair()) }
+
+func Stmt() { use(pair()) }
+
+// Split and Single don't forward the results of a call with several
+// of them.
+func Split() error {
+	n, err := pair()
+	return use(n, err)
+}
+
+func Single() { check(one()) }
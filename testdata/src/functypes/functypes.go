@@ -0,0 +1,4 @@
+package functypes
+
+func Walk(fn func(string, int) error) error { return nil }
+func Visit(fn func(string) error) error     { return nil }
@@ -0,0 +1,12 @@
+package funcvars
+
+import "io"
+
+// Hook is called for each reader.
+var Hook func(io.Reader) error
+
+var Hooks []func(io.Reader) error
+
+var Count int
+
+func Handle(r io.Reader) error { return nil }
@@ -0,0 +1,18 @@
+package ifaces
+
+type Store interface {
+	Get(key string) ([]byte, error)
+	Len() int
+}
+
+// Flusher embeds Store, whose methods it doesn't declare itself.
+type Flusher interface {
+	Store
+	Flush() error
+}
+
+type memory struct{}
+
+func (memory) Put(key string) error { return nil }
+
+func Open() error { return nil }
@@ -0,0 +1,21 @@
+// Package matching declares functions for testing how queries compare
+// types.
+package matching
+
+import "io"
+
+func Recv() <-chan int     { return nil }
+func Send() chan<- int     { return nil }
+func Both() chan int       { return nil }
+func Texts() <-chan string { return nil }
+
+func Counts() map[string]int   { return nil }
+func Index() map[int]string    { return nil }
+func Names() map[string]string { return nil }
+
+func ReadClose(rc io.ReadCloser)            {}
+func ReadAndClose(r io.Reader, c io.Closer) {}
+func ReadOnly(r io.Reader)                  {}
+
+func Pair(n int, s string) bool { return false }
+func Swap(s string, n int)      {}
@@ -0,0 +1,13 @@
+package methods
+
+type Small struct{}
+
+func (Small) Get() int { return 0 }
+
+type Big struct{}
+
+func (Big) Len() int { return 0 }
+func (*Big) Put(int) {}
+func (*Big) Reset()  {}
+
+func Free() {}
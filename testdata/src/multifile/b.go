@@ -0,0 +1,4 @@
+package multifile
+
+func Write() error { return nil }
+func Close() error { return nil }
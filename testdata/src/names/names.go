@@ -0,0 +1,9 @@
+package names
+
+func Open(name string) (fd int, err error) { return 0, nil }
+func Rename(oldName, newName string) error { return nil }
+func Close(fd int) (err error)             { return nil }
+
+// Unnamed and blank parameters and results never match.
+func Unnamed(string) int       { return 0 }
+func Blank(_ string) (_ error) { return nil }
@@ -0,0 +1,13 @@
+package outparams
+
+type Config struct{}
+
+func Decode(data []byte, dst *Config) error { return nil }
+func Fill(dst *[]string)                    {}
+func Load(name string, cfg *Config)         {}
+
+// None of these use the out-parameter idiom.
+func Parse(data []byte) (*Config, error) { return nil, nil }
+func Read(dst *Config) (int, error)      { return 0, nil }
+func Count(cfg Config) error             { return nil }
+func Apply(cfg *Config) bool             { return false }
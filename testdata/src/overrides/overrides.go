@@ -0,0 +1,23 @@
+package overrides
+
+import "io"
+
+type base struct{}
+
+func (base) Close() error { return nil }
+func (*base) Reset()      {}
+
+// Conn redeclares Close of base and Read of io.Reader, but not Reset.
+type Conn struct {
+	base
+	io.Reader
+}
+
+func (Conn) Close() error                { return nil }
+func (*Conn) Read(p []byte) (int, error) { return 0, nil }
+func (Conn) Flush()                      {}
+
+// Plain embeds nothing, so its Close shadows nothing.
+type Plain struct{}
+
+func (Plain) Close() error { return nil }
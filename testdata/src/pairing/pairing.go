@@ -0,0 +1,25 @@
+package pairing
+
+type User struct{}
+
+type UserError struct{}
+
+func (*UserError) Error() string { return "" }
+
+// UserID has the prefix of User, but isn't an error.
+type UserID int
+
+type Item struct{}
+
+type ItemErr string
+
+func (ItemErr) Error() string { return "" }
+
+// Order has no error type of its own.
+type Order struct{}
+
+func GetUser() (*User, error)   { return nil, nil }
+func GetItem() (Item, error)    { return Item{}, nil }
+func GetOrder() (*Order, error) { return nil, nil }
+func FindUser() (*User, bool)   { return nil, false }
+func Users() ([]*User, error)   { return nil, nil }
@@ -0,0 +1,8 @@
+package a
+
+import "platform/b"
+
+func F() b.T {
+	var t b.T
+	return t
+}
@@ -0,0 +1,6 @@
+//go:build ignore
+// +build ignore
+
+package ignored
+
+func F() {}
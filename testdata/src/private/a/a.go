@@ -0,0 +1,8 @@
+package a
+
+type cache struct{}
+
+func newCache() *cache         { return nil }
+func (c *cache) merge(o cache) {}
+func Use(c cache)              {}
+func Other(n int)              {}
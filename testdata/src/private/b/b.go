@@ -0,0 +1,6 @@
+package b
+
+// cache has the same name as a's, but is a different type.
+type cache int
+
+func Fill(c cache) {}
@@ -0,0 +1,14 @@
+package producers
+
+type Event struct{}
+
+func Ints() <-chan int                      { return nil }
+func Lines() (<-chan string, error)         { return nil, nil }
+func Both(n int) chan int                   { return nil }
+func Stream() (chan<- int, <-chan struct{}) { return nil, nil }
+func Events() <-chan Event                  { return nil }
+
+// None of these return a channel that can be received from.
+func Sink() chan<- int      { return nil }
+func Consume(ch <-chan int) {}
+func Slice() []int          { return nil }
@@ -0,0 +1,32 @@
+package reach
+
+func Exported() { helper() }
+func helper()   { deeper() }
+func deeper()   {}
+
+var Hook = hooked
+
+func hooked() {}
+
+func init()     { fromInit() }
+func fromInit() {}
+
+var _ = fromBlank()
+
+func fromBlank() int { return 0 }
+
+// Reaching T reaches conn and its methods.
+type T struct{ c conn }
+
+type conn struct{}
+
+func (conn) close() {}
+
+// Neither unused, alsoUnused nor orphan's methods are reachable from
+// the exported API.
+func unused()     { alsoUnused() }
+func alsoUnused() {}
+
+type orphan struct{}
+
+func (orphan) Run() {}
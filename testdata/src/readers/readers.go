@@ -0,0 +1,13 @@
+package readers
+
+import "io"
+
+type File struct{}
+
+func (*File) Read(p []byte) (int, error) { return 0, nil }
+
+func FromFile(f *File)           {}
+func FromValue(f File)           {}
+func FromReader(r io.Reader)     {}
+func FromCloser(c io.ReadCloser) {}
+func FromInt(n int)              {}
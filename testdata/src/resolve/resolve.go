@@ -0,0 +1,19 @@
+package resolve
+
+import "io"
+
+type handler func(io.Reader) error
+
+type local struct{}
+
+// Map returns a map.
+func Map() map[string]int              { return nil }
+func Chan() chan int                   { return nil }
+func Func() func(io.Reader) error      { return nil }
+func Handler() handler                 { return nil }
+func Empty() interface{}               { return nil }
+func Struct() struct{ X int }          { return struct{ X int }{} }
+func Array() [4]byte                   { return [4]byte{} }
+func Local() *local                    { return nil }
+func Reader() io.Reader                { return nil }
+func Nested() map[string][]chan<- bool { return nil }
@@ -0,0 +1,14 @@
+package selfcons
+
+type Iface interface{ M() }
+
+type Impl struct{}
+
+func (*Impl) M() {}
+
+type Other struct{}
+
+func Both() (*Impl, Iface, error) { return nil, nil, nil }
+func Mismatch() (*Other, Iface)   { return nil, nil }
+func Single() Iface               { return nil }
+func Concrete() (*Impl, *Impl)    { return nil, nil }
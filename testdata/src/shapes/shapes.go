@@ -0,0 +1,12 @@
+package shapes
+
+type File struct{}
+
+func Open(name string, flag int) error         { return nil }
+func Create(path string, mode int) (err error) { return nil }
+func (File) Write(s string, n int) error       { return nil }
+
+func Close() error { return nil }
+func Reset() error { return nil }
+
+func Count(s string) (int, error) { return 0, nil }
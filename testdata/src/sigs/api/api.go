@@ -0,0 +1,7 @@
+package api
+
+import "io"
+
+type Request struct{}
+
+type HandlerFunc func(w io.Writer, r *Request)
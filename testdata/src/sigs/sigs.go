@@ -0,0 +1,19 @@
+package sigs
+
+import (
+	"sigs/api"
+
+	"bytes"
+	"io"
+)
+
+func Serve(out io.Writer, req *api.Request) {}
+
+// Neither Fails nor Buffered has the signature of api.HandlerFunc.
+func Fails(w io.Writer, r *api.Request) error  { return nil }
+func Buffered(w *bytes.Buffer, r *api.Request) {}
+
+type Server struct{}
+
+// Handle is assignable to api.HandlerFunc as the method value s.Handle.
+func (Server) Handle(w io.Writer, r *api.Request) {}
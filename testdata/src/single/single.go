@@ -0,0 +1,17 @@
+package single
+
+type T struct{}
+
+type Flag bool
+
+func Ptr() *T          { return nil }
+func Err() error       { return nil }
+func OK() bool         { return false }
+func IsSet(n int) bool { return false }
+func Name() string     { return "" }
+
+// None of these return just a pointer, error, bool or string.
+func Both() (*T, error) { return nil, nil }
+func Flagged() Flag     { return false }
+func Count() int        { return 0 }
+func Nothing()          {}
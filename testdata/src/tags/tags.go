@@ -0,0 +1,9 @@
+package tags
+
+type User struct {
+	Name     string `json:"name"`
+	Password string `json:"-"`
+	Age      int    `json:"age,omitempty" db:"age"`
+	Email    string `db:"email"`
+	internal int
+}
@@ -0,0 +1,3 @@
+package typeerr
+
+var X int = "not an int"
@@ -0,0 +1,35 @@
+package typesearch
+
+import "io"
+
+type File struct {
+	Name string
+	Size int64
+}
+
+func (*File) Read(p []byte) (int, error) { return 0, nil }
+func (*File) Close() error               { return nil }
+
+type Buffer []byte
+
+func (Buffer) Read(p []byte) (int, error) { return 0, nil }
+
+type Nop struct{}
+
+func (Nop) Close() error { return nil }
+
+type Stream interface {
+	io.Reader
+	io.Closer
+}
+
+type Meta struct {
+	Size int64
+	Tags []string
+}
+
+type name string
+
+type Entry struct {
+	Key name
+}
@@ -0,0 +1,11 @@
+package variadic
+
+type Any interface{}
+
+func Println(args ...interface{})             {}
+func Logf(format string, args ...interface{}) {}
+func Anyf(args ...Any)                        {}
+func Join(sep string, parts ...string) string { return "" }
+func Concat(parts []string) string            { return "" }
+func Sum(ns ...int) int                       { return 0 }
+func Plain(x interface{})                     {}
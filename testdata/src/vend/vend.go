@@ -0,0 +1,5 @@
+package vend
+
+import "example.org/lib"
+
+func Use(t *lib.T) lib.T { return *t }
@@ -0,0 +1,3 @@
+package withtests
+
+func mustOpen() error { return Open() }
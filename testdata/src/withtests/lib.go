@@ -0,0 +1,3 @@
+package withtests
+
+func Open() error { return nil }
@@ -0,0 +1,3 @@
+package onlytests
+
+func fixture() error { return nil }
@@ -0,0 +1,8 @@
+package withtests_test
+
+import "withtests"
+
+func openTwice() error {
+	withtests.Open()
+	return withtests.Open()
+}
@@ -0,0 +1,8 @@
+package a
+
+import (
+	"example.com/b"
+	"example.com/c"
+)
+
+func F(b.B) c.C { return c.C{} }
@@ -0,0 +1,5 @@
+package b
+
+type B int
+
+func New() B { return 0 }
@@ -0,0 +1,87 @@
+package uses
+
+import (
+	"golang.org/x/tools/go/types"
+)
+
+// TypeQuery describes the named types to look for. All criteria
+// that are set have to be satisfied.
+type TypeQuery struct {
+	// Implements lists interfaces that the type, or a pointer to it,
+	// has to implement.
+	Implements []string
+	// HasField lists types that the type, which has to be a struct,
+	// needs fields of. They are matched like the types of Query.Args,
+	// so the unexported types of the type's package can be named
+	// without qualification.
+	HasField []string
+}
+
+// TypeMatch is a named type that satisfied a type query.
+type TypeMatch struct {
+	Named *types.Named
+	// PointerOnly is set if only a pointer to the type implements
+	// the interfaces in the query.
+	PointerOnly bool
+	// Fields are the fields whose types matched the query.
+	Fields []*types.Var
+}
+
+// Types returns all named types in the snapshot that satisfy q, in
+// the order they were loaded.
+func (s *Snapshot) Types(q TypeQuery) ([]TypeMatch, error) {
+	var ifaces []*types.Interface
+	for _, name := range q.Implements {
+		iface, err := s.lookupInterface(name)
+		if err != nil {
+			return nil, err
+		}
+		ifaces = append(ifaces, iface)
+	}
+
+	scoped := s.newScopedQueries(Query{Args: q.HasField})
+	var matches []TypeMatch
+typeLoop:
+	for _, named := range s.named {
+		m := TypeMatch{Named: named}
+		for _, iface := range ifaces {
+			if types.Implements(named, iface) {
+				continue
+			}
+			if _, ok := named.Underlying().(*types.Interface); ok || !types.Implements(types.NewPointer(named), iface) {
+				continue typeLoop
+			}
+			m.PointerOnly = true
+		}
+
+		if len(q.HasField) > 0 {
+			st, ok := named.Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+			pq, ok := scoped.get(named.Obj().Pkg())
+			if !ok {
+				continue
+			}
+			for _, typ := range q.HasField {
+				matched := pq.Matcher([]string{typ})
+				found := false
+				for i := 0; i < st.NumFields(); i++ {
+					if matched(st.Field(i).Type()) {
+						m.Fields = append(m.Fields, st.Field(i))
+						found = true
+					}
+				}
+				if !found {
+					continue typeLoop
+				}
+			}
+		}
+
+		matches = append(matches, m)
+	}
+	if err := scoped.err(); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
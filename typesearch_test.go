@@ -0,0 +1,58 @@
+package uses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		query TypeQuery
+		// want lists the matching types, followed by * if only a
+		// pointer to them matched, and the names of matched fields.
+		want []string
+	}{
+		{"reader", TypeQuery{Implements: []string{"io.Reader"}}, []string{"Buffer", "File*", "Stream"}},
+		{"read closer", TypeQuery{Implements: []string{"io.ReadCloser"}}, []string{"File*", "Stream"}},
+		{"several interfaces", TypeQuery{Implements: []string{"io.Reader", "io.Closer"}}, []string{"File*", "Stream"}},
+		{"closer", TypeQuery{Implements: []string{"io.Closer"}}, []string{"File*", "Nop", "Stream"}},
+		{"writer", TypeQuery{Implements: []string{"io.Writer"}}, nil},
+		{"field", TypeQuery{HasField: []string{"int64"}}, []string{"File Size", "Meta Size"}},
+		{"fields", TypeQuery{HasField: []string{"int64", "[]string"}}, []string{"Meta Size Tags"}},
+		{"field and interface", TypeQuery{Implements: []string{"io.Reader"}, HasField: []string{"int64"}}, []string{"File* Size"}},
+		// Field types are matched like the types of Args.
+		{"unexported field type", TypeQuery{HasField: []string{"name"}}, []string{"Entry Key"}},
+		{"qualified field type", TypeQuery{HasField: []string{"typesearch.name"}}, []string{"Entry Key"}},
+		{"string field", TypeQuery{HasField: []string{"string"}}, []string{"File Name"}},
+	}
+	s := loadTest(t, "typesearch")
+	for _, tt := range tests {
+		matches, err := s.Types(tt.query)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, m := range matches {
+			desc := m.Named.Obj().Name()
+			if m.PointerOnly {
+				desc += "*"
+			}
+			for _, f := range m.Fields {
+				desc += " " + f.Name()
+			}
+			got = append(got, desc)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTypesNotInterface(t *testing.T) {
+	s := loadTest(t, "typesearch")
+	if _, err := s.Types(TypeQuery{Implements: []string{"typesearch.File"}}); err == nil {
+		t.Error("got no error for a struct type")
+	}
+}
@@ -0,0 +1,239 @@
+package uses
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// workspace is a multi-module workspace as described by a go.work
+// file.
+type workspace struct {
+	// modules maps module paths to their directories.
+	modules map[string]string
+	// requires maps the modules required by the workspace's modules
+	// to their directories in the module cache. If several versions
+	// of a module are required, the first one wins.
+	requires map[string]string
+}
+
+// UseWorkspace makes ctx find packages in the modules of the go.work
+// file named by $GOWORK or found in dir or its parents, if any.
+func (ctx *Context) UseWorkspace(dir string) {
+	ctx.workspace = findWorkspace(dir, ctx.modCacheDir(), ctx.Log)
+}
+
+// findWorkspace looks for the go.work file named by $GOWORK or in dir
+// and its parents, resolving the modules it requires in the module
+// cache at cache. It returns nil if there is none.
+func findWorkspace(dir, cache string, log Logger) *workspace {
+	file := os.Getenv("GOWORK")
+	if file == "off" {
+		return nil
+	}
+	if file == "" {
+		for {
+			candidate := filepath.Join(dir, "go.work")
+			if _, err := os.Stat(candidate); err == nil {
+				file = candidate
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return nil
+			}
+			dir = parent
+		}
+	}
+
+	dirs, err := parseDirectives(file, "use")
+	if err != nil {
+		log.Warnf("Couldn't read %s: %s", file, err)
+		return nil
+	}
+	ws := &workspace{modules: make(map[string]string), requires: make(map[string]string)}
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(file), dir)
+		}
+		paths, err := parseDirectives(filepath.Join(dir, "go.mod"), "module")
+		if err != nil || len(paths) == 0 {
+			log.Warnf("Couldn't determine the module in %s", dir)
+			continue
+		}
+		log.Infof("Using workspace module %s in %s", paths[0], dir)
+		ws.modules[paths[0]] = dir
+
+		if cache == "" {
+			continue
+		}
+		requires, err := parseDirectives(filepath.Join(dir, "go.mod"), "require")
+		if err != nil {
+			log.Warnf("Couldn't read the requirements of %s: %s", paths[0], err)
+			continue
+		}
+		for _, req := range requires {
+			fields := strings.Fields(req)
+			if len(fields) < 2 {
+				continue
+			}
+			if _, ok := ws.requires[fields[0]]; !ok {
+				ws.requires[fields[0]] = filepath.Join(cache, filepath.FromSlash(escapeModPath(fields[0])+"@"+fields[1]))
+			}
+		}
+	}
+	return ws
+}
+
+// escapeModPath applies the module cache's case encoding to path,
+// the reverse of unescapeModPath.
+func escapeModPath(path string) string {
+	var buf []rune
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			buf = append(buf, '!', unicode.ToLower(r))
+			continue
+		}
+		buf = append(buf, r)
+	}
+	return string(buf)
+}
+
+// parseDirectives returns the arguments of all directives named
+// directive in a go.mod or go.work file, supporting both the single
+// line and the block form.
+func parseDirectives(file, directive string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var args []string
+	inBlock := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "//"); index != -1 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			args = append(args, strings.Trim(line, `"`))
+		case line == directive+" (":
+			inBlock = true
+		case strings.HasPrefix(line, directive+" "):
+			args = append(args, strings.Trim(strings.TrimSpace(line[len(directive):]), `"`))
+		}
+	}
+	return args, scanner.Err()
+}
+
+// dir returns the directory of the package path if it belongs to one
+// of the workspace's modules or the modules they require. The
+// workspace's modules take precedence.
+func (ws *workspace) dir(path string) (string, bool) {
+	if ws == nil {
+		return "", false
+	}
+	for _, modules := range []map[string]string{ws.modules, ws.requires} {
+		best := ""
+		for mod := range modules {
+			if (path == mod || strings.HasPrefix(path, mod+"/")) && len(mod) > len(best) {
+				best = mod
+			}
+		}
+		if best != "" {
+			return filepath.Join(modules[best], filepath.FromSlash(strings.TrimPrefix(path[len(best):], "/"))), true
+		}
+	}
+	return "", false
+}
+
+// importPath returns the import path of the package in dir if it
+// belongs to one of the workspace's modules.
+func (ws *workspace) importPath(dir string) (string, bool) {
+	if ws == nil {
+		return "", false
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	best, bestRel := "", ""
+	for mod, root := range ws.modules {
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if best == "" || len(root) > len(ws.modules[best]) {
+			best, bestRel = mod, rel
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	if bestRel == "." {
+		return best, true
+	}
+	return best + "/" + filepath.ToSlash(bestRel), true
+}
+
+// expand returns the packages in the workspace's modules that
+// match pattern.
+func (ws *workspace) expand(pattern string) []string {
+	if ws == nil {
+		return nil
+	}
+	match := matchPattern(pattern)
+	var mods []string
+	for mod := range ws.modules {
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	var paths []string
+	for _, mod := range mods {
+		root := ws.modules[mod]
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.IsDir() {
+				return nil
+			}
+			name := info.Name()
+			if path != root {
+				if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					// Nested module
+					return filepath.SkipDir
+				}
+			}
+			rel, _ := filepath.Rel(root, path)
+			importPath := mod
+			if rel != "." {
+				importPath += "/" + filepath.ToSlash(rel)
+			}
+			if match(importPath) && hasGoFiles(path) {
+				paths = append(paths, importPath)
+			}
+			return nil
+		})
+	}
+	return paths
+}
+
+func hasGoFiles(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, m := range matches {
+		if !strings.HasSuffix(m, "_test.go") {
+			return true
+		}
+	}
+	return false
+}
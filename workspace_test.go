@@ -0,0 +1,113 @@
+package uses
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// chdir changes the working directory to dir and returns a function
+// restoring it.
+func chdir(t *testing.T, dir string) func() {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return func() { os.Chdir(wd) }
+}
+
+func TestWorkspaceResolvePackages(t *testing.T) {
+	ctx := newTestContext(t)
+	dir := filepath.Join(ctx.Build.GOPATH, "work")
+	defer chdir(t, dir)()
+	ctx.UseWorkspace(dir)
+
+	tests := []struct {
+		entries []string
+		want    []string
+	}{
+		{[]string{"./..."}, []string{"example.com/a", "example.com/b"}},
+		{[]string{"example.com/..."}, []string{"example.com/a", "example.com/b"}},
+		{[]string{"./b", "example.com/b"}, []string{"example.com/b"}},
+		{[]string{"./...", "!example.com/a"}, []string{"example.com/b"}},
+	}
+	for _, tt := range tests {
+		if got := ctx.ResolvePackages(tt.entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResolvePackages(%q) = %q, want %q", tt.entries, got, tt.want)
+		}
+	}
+}
+
+func TestWorkspaceModules(t *testing.T) {
+	ctx := newTestContext(t)
+	log := &recordLogger{}
+	ctx.Log = log
+	dir := filepath.Join(ctx.Build.GOPATH, "work")
+	// The go.work file is found in the parents of dir.
+	ctx.UseWorkspace(filepath.Join(dir, "a"))
+
+	want := []string{
+		"Using workspace module example.com/a in " + filepath.Join(dir, "a"),
+		"Using workspace module example.com/b in " + filepath.Join(dir, "b"),
+	}
+	if !reflect.DeepEqual(log.info, want) {
+		t.Errorf("got %q, want %q", log.info, want)
+	}
+	if len(log.warnings) > 0 {
+		t.Errorf("got warnings %q", log.warnings)
+	}
+}
+
+func TestWorkspaceGOWORK(t *testing.T) {
+	ctx := newTestContext(t)
+	file := filepath.Join(ctx.Build.GOPATH, "work", "go.work")
+	defer os.Unsetenv("GOWORK")
+
+	os.Setenv("GOWORK", "off")
+	ctx.UseWorkspace(filepath.Dir(file))
+	if ctx.workspace != nil {
+		t.Error("GOWORK=off: got a workspace")
+	}
+
+	// $GOWORK names the go.work file, even outside of the workspace.
+	os.Setenv("GOWORK", file)
+	ctx.UseWorkspace(ctx.Build.GOPATH)
+	if got, want := ctx.workspace.expand("..."), []string{"example.com/a", "example.com/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GOWORK=%s: got %q, want %q", file, got, want)
+	}
+}
+
+func TestWorkspaceImports(t *testing.T) {
+	ctx := newTestContext(t)
+	dir := filepath.Join(ctx.Build.GOPATH, "work")
+	defer chdir(t, dir)()
+	ctx.UseWorkspace(dir)
+
+	s := Load(ctx, ctx.ResolvePackages([]string{"./..."}))
+	if len(s.Errors) > 0 {
+		t.Fatal(s.Errors)
+	}
+	tests := []struct {
+		query Query
+		want  []string
+	}{
+		// example.com/b is imported from another module of the
+		// workspace, example.com/c from the module cache.
+		{Query{Args: []string{"example.com/b.B"}}, []string{"F"}},
+		{Query{Rets: []string{"example.com/c.C"}}, []string{"F"}},
+		{Query{Rets: []string{"example.com/b.B"}}, []string{"New"}},
+	}
+	for _, tt := range tests {
+		matches, err := s.Match(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matchNames(matches); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}